@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/moocss/apiserver/src/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// The options of `config init`.
+	initOutput string
+	initForce  bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Validate, show and generate the configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := config.Load(configFile, profile); err != nil {
+			return err
+		}
+		fmt.Println("Configuration is valid.")
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration with the source of each value",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := config.Load(configFile, profile)
+		if err != nil {
+			return err
+		}
+
+		// The same overrides as the server options.
+		var flags []string
+		if address != "" {
+			conf.Core.Address = address
+			flags = append(flags, "core.address")
+		}
+		if port != "" {
+			conf.Core.Port = port
+			flags = append(flags, "core.port")
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, s := range config.Settings(&conf, flags...) {
+			fmt.Fprintf(w, "%s:\t%s\t# %s\n", s.Key, strconv.Quote(s.Value), s.Source)
+		}
+		return w.Flush()
+	},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write the commented default configuration",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if initOutput == "" {
+			_, err := os.Stdout.Write(config.Template())
+			return err
+		}
+		if _, err := os.Stat(initOutput); err == nil && !initForce {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", initOutput)
+		}
+		if err := ioutil.WriteFile(initOutput, config.Template(), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", initOutput)
+		return nil
+	},
+}
+
+func init() {
+	configInitCmd.Flags().StringVarP(&initOutput, "output", "o", "", "Write to the file instead of stdout")
+	configInitCmd.Flags().BoolVarP(&initForce, "force", "f", false, "Overwrite the existing file")
+
+	configCmd.AddCommand(configValidateCmd, configShowCmd, configInitCmd)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moocss/apiserver/src/config"
+	"github.com/spf13/cobra"
+)
+
+// The options of `healthcheck`.
+var (
+	healthcheckURL     string
+	healthcheckTimeout time.Duration
+	healthcheckReady   bool
+)
+
+var healthcheckCmd = &cobra.Command{
+	Use:   "healthcheck",
+	Short: "Probe /sd/health of the local server, exit non-zero if it's unhealthy",
+	Long: `Probe /sd/health of the local server, or /sd/ready with --ready, exit non-zero if it's unhealthy.
+It's used as the container health check, e.g. HEALTHCHECK CMD ["apiserver", "healthcheck"]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url := healthcheckURL
+		if url == "" {
+			conf, err := config.Load(configFile, profile)
+			if err != nil {
+				return err
+			}
+			// The same overrides as the server options.
+			if address != "" {
+				conf.Core.Address = address
+			}
+			if port != "" {
+				conf.Core.Port = port
+			}
+			path := "/sd/health"
+			if healthcheckReady {
+				path = "/sd/ready"
+			}
+			url = conf.LocalURL(path)
+		}
+
+		client := &http.Client{
+			Timeout: healthcheckTimeout,
+			// The certificate is issued for the public host, not localhost.
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+		resp, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s returned %s", url, resp.Status)
+		}
+		fmt.Println("OK")
+		return nil
+	},
+}
+
+func init() {
+	healthcheckCmd.Flags().StringVar(&healthcheckURL, "url", "", "The health check url (default: /sd/health of the configured listener)")
+	healthcheckCmd.Flags().BoolVar(&healthcheckReady, "ready", false, "Probe /sd/ready instead, it also checks the dependencies")
+	healthcheckCmd.Flags().DurationVar(&healthcheckTimeout, "timeout", 3*time.Second, "The timeout of the probe")
+}
@@ -1,106 +1,67 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
-	logger "github.com/lexkong/log"
+	"os"
+
 	"github.com/moocss/apiserver/src"
 	"github.com/moocss/apiserver/src/config"
-	"github.com/moocss/apiserver/src/service"
 	v "github.com/moocss/apiserver/src/pkg/version"
-	"github.com/spf13/pflag"
-	"os"
-	"golang.org/x/sync/errgroup"
+	"github.com/spf13/cobra"
 )
 
-var usageStr = `
+var banner = `
               .__                                        
 _____  ______ |__| ______ ______________  __ ___________ 
 \__  \ \____ \|  |/  ___// __ \_  __ \  \/ // __ \_  __ \
  / __ \|  |_> >  |\___ \\  ___/|  | \/\   /\  ___/|  | \/
 (____  /   __/|__/____  >\___  >__|    \_/  \___  >__|   
      \/|__|           \/     \/                 \/       
-
-Usage: apiserver [options]
-
-Server Options:
-	-c, --config <file>              Configuration file path
-	-a, --address <address>          Address to bind (default: any)
-	-p, --port <port>                Use port for clients (default: 9090)
-Common Options:
-	-h, --help                       Show this message
-	-v, --version                    Show version
 `
 
-func main() {
-	opts := config.ConfYaml{}
-
-	var (
-		showVersion bool
-		configFile  string
-	)
-
-	pflag.StringVar(&configFile, "c", "", "Configuration file path.")
-	pflag.StringVar(&configFile, "config", "", "Configuration file path.")
-	pflag.BoolVar(&showVersion, "v", false, "Print version information.")
-	pflag.BoolVar(&showVersion, "version", false, "Print version information.")
-	pflag.StringVar(&opts.Core.Address, "a", "", "address to bind")
-	pflag.StringVar(&opts.Core.Address, "address", "", "address to bind")
-	pflag.StringVar(&opts.Core.Port, "p", "", "port number for gorush")
-	pflag.StringVar(&opts.Core.Port, "port", "", "port number for gorush")
-	pflag.Usage = usage
-	pflag.Parse()
-
-	v.SetVersion(src.Version)
+// The global options of the commands.
+var (
+	configFile  string
+	profile     string
+	showVersion bool
+)
 
-	if showVersion {
-		v := v.Get()
-		marshalled, err := json.MarshalIndent(&v, "", "  ")
-		if err != nil {
-			fmt.Printf("%v\n", err)
-			os.Exit(1)
+var rootCmd = &cobra.Command{
+	Use:           "apiserver",
+	Short:         "基于 Gin 框架构建的 RESTful API 服务",
+	Long:          banner,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Args:          cobra.NoArgs,
+	// 不带子命令时启动服务, 与 `apiserver serve` 相同
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if showVersion {
+			return versionCmd.RunE(cmd, args)
 		}
+		return serve()
+	},
+}
 
-		fmt.Println(string(marshalled))
-		return
-	}
-
-	var err error
-	// set default parameters.
-	src.Conf, err = config.Init(configFile)
-	if err != nil {
-		fmt.Printf("Load yaml config file error: '%v'", err)
-		return
-	}
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Configuration file path")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Merge the profile file, e.g. config.prod.yaml (env: APISERVER_PROFILE)")
+	rootCmd.PersistentFlags().StringVarP(&address, "address", "a", "", "Address to bind, overrides core.address (default: any)")
+	rootCmd.PersistentFlags().StringVarP(&port, "port", "p", "", "Use port for clients, overrides core.port (default: 9090)")
+	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Print the version information, the same as `apiserver version`")
 
-	// overwrite server port and address
-	if opts.Core.Port != "" {
-		src.Conf.Core.Port = opts.Core.Port
-	}
-	if opts.Core.Address != "" {
-		src.Conf.Core.Address = opts.Core.Address
-	}
+	rootCmd.AddCommand(serveCmd, migrateCmd, configCmd, userCmd, healthcheckCmd, versionCmd)
+}
 
-	// init db
-	service.DB.Init()
+// initConfig loads the config and initializes the log package.
+func initConfig() (err error) {
+	src.Conf, err = config.Init(configFile, profile)
+	return err
+}
 
-	var g errgroup.Group
-	g.Go(func() error {
-		// 启动服务
-		return src.RunHTTPServer()
-	})
-	g.Go(func() error {
-		// 健康检查
-		return src.PingServer()
-	})
+func main() {
+	v.SetVersion(src.Version)
 
-	if err = g.Wait(); err != nil {
-		logger.Error("接口服务出错了：", err)
+	if err := rootCmd.Execute(); err != nil {
+		rootCmd.PrintErrln("Error:", err)
+		os.Exit(1)
 	}
 }
-
-// usage will print out the flag options for the server.
-func usage() {
-	fmt.Printf("%s\n", usageStr)
-	os.Exit(0)
-}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/moocss/apiserver/src"
+	"github.com/moocss/apiserver/src/migration"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/spf13/cobra"
+)
+
+// migrationDir is the directory of the migration files.
+var migrationDir string
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database migrations",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all the pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(func(m *migration.Migrator) error {
+			done, err := m.Up()
+			for _, mg := range done {
+				fmt.Printf("Applied %d_%s\n", mg.Version, mg.Name)
+			}
+			if err == nil && len(done) == 0 {
+				fmt.Println("No pending migrations.")
+			}
+			return err
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [n]",
+	Short: "Roll back the latest n migrations (default: 1)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 1
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[0])
+			}
+		}
+
+		return withMigrator(func(m *migration.Migrator) error {
+			done, err := m.Down(n)
+			for _, mg := range done {
+				fmt.Printf("Rolled back %d_%s\n", mg.Version, mg.Name)
+			}
+			return err
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(func(m *migration.Migrator) error {
+			status, err := m.Status(context.Background())
+			if err != nil {
+				return err
+			}
+			for _, s := range status {
+				state := "pending"
+				if s.Dirty {
+					state = "dirty"
+				} else if s.Applied {
+					state = "applied at " + s.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Printf("%d_%-40s %s\n", s.Version, s.Name, state)
+			}
+			return nil
+		})
+	},
+}
+
+// forcePending removes the forced version instead of recording it as applied.
+var forcePending bool
+
+var migrateForceCmd = &cobra.Command{
+	Use:   "force <version>",
+	Short: "Clear the dirty flag of a migration fixed by hand",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+
+		return withMigrator(func(m *migration.Migrator) error {
+			if err := m.Force(version, !forcePending); err != nil {
+				return err
+			}
+			fmt.Printf("Forced %d\n", version)
+			return nil
+		})
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a new empty migration file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := migration.Create(migrationDir, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", file)
+		return nil
+	},
+}
+
+func init() {
+	migrateCreateCmd.Flags().StringVar(&migrationDir, "dir", "src/migration", "Directory of the migration files")
+
+	migrateForceCmd.Flags().BoolVar(&forcePending, "pending", false, "Record the version as not applied")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd, migrateForceCmd, migrateCreateCmd)
+}
+
+// withMigrator runs fn with the migrator of the configured database.
+func withMigrator(fn func(m *migration.Migrator) error) error {
+	if err := initConfig(); err != nil {
+		return err
+	}
+
+	// SQLite schema is created by AutoMigrate, the migrations are written for MySQL.
+	if src.Conf.Db.Driver == "sqlite3" {
+		return errors.New("the migrations are for MySQL, SQLite creates the tables on startup")
+	}
+
+	db, err := service.InitSelfDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return fn(migration.New(db))
+}
+
+// migrateUp applies the pending migrations before the database is initialized.
+func migrateUp() error {
+	// SQLite schema is created by AutoMigrate.
+	if src.Conf.Db.Driver == "sqlite3" {
+		return nil
+	}
+
+	db, err := service.InitSelfDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = migration.New(db).Up()
+	return err
+}
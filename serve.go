@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+
+	logger "github.com/lexkong/log"
+	"github.com/moocss/apiserver/src"
+	"github.com/moocss/apiserver/src/pkg/shutdown"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// The server options, they override the config.
+var (
+	address string
+	port    string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Start the api server, the default command",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serve()
+	},
+}
+
+func serve() error {
+	if err := initConfig(); err != nil {
+		return err
+	}
+
+	// overwrite server port and address
+	if port != "" {
+		src.Conf.Core.Port = port
+	}
+	if address != "" {
+		src.Conf.Core.Address = address
+	}
+
+	// apply the pending migrations
+	if src.Conf.Db.AutoMigrate {
+		if err := migrateUp(); err != nil {
+			return err
+		}
+	}
+
+	// init db
+	if err := service.DB.Init(); err != nil {
+		return err
+	}
+
+	// 配置文件变化时应用新的配置
+	src.ApplyConfig()
+
+	// 优雅退出: 先停止 HTTP 服务, 再停止后台任务, 最后关闭数据库
+	shutdown.Register(shutdown.PhaseDB, "database", func(ctx context.Context) error {
+		service.DB.Close()
+		return nil
+	})
+	shutdown.Notify(src.Conf.Core.ShutdownTimeout, src.Conf.Core.ShutdownDelay)
+
+	var g errgroup.Group
+	g.Go(func() error {
+		// 启动服务
+		return src.RunHTTPServer()
+	})
+	g.Go(func() error {
+		// 健康检查
+		return src.PingServer()
+	})
+
+	err := g.Wait()
+	if err != nil {
+		logger.Error("接口服务出错了：", err)
+	}
+
+	// 等待处理中的请求结束
+	if shutdown.Draining() {
+		<-shutdown.Done()
+	}
+
+	return err
+}
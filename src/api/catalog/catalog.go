@@ -0,0 +1,54 @@
+package catalog
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/util"
+)
+
+// Entry is an errno in the error catalog.
+type Entry struct {
+	Code        int               `json:"code"`
+	Module      string            `json:"module"`
+	HTTPStatus  int               `json:"httpStatus"`
+	Message     string            `json:"message"`
+	Messages    map[string]string `json:"messages"`
+	Description string            `json:"description"`
+}
+
+type ListResponse struct {
+	TotalCount int      `json:"totalCount"`
+	Errors     []*Entry `json:"errors"`
+}
+
+// @Summary List the error codes
+// @Description List the registered error codes with the http status and the messages in each language
+// @Tags errors
+// @Produce  json
+// @Param module query string false "Only the errors of the module, e.g. user"
+// @Success 200 {object} catalog.ListResponse "{"code":0,"message":"OK","data":{"totalCount":1,"errors":[{"code":20102,"module":"user","httpStatus":404,"message":"The user was not found.","messages":{"en":"The user was not found.","zh-CN":"用户不存在。"},"description":"No user has the id or the username."}]}}"
+// @Router /errors [get]
+func List(c *gin.Context) {
+	module := c.Query("module")
+	lang := util.Language(c)
+
+	entries := make([]*Entry, 0)
+	for _, e := range errno.All() {
+		if module != "" && e.Module != module {
+			continue
+		}
+		entries = append(entries, &Entry{
+			Code:        e.Code,
+			Module:      e.Module,
+			HTTPStatus:  e.HTTPStatus,
+			Message:     errno.Translate(e.Code, e.Message, lang),
+			Messages:    errno.Messages(e),
+			Description: e.Description,
+		})
+	}
+
+	util.SendResponse(c, nil, ListResponse{
+		TotalCount: len(entries),
+		Errors:     entries,
+	})
+}
@@ -0,0 +1,163 @@
+package role
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lexkong/log"
+	"github.com/lexkong/log/lager"
+	"github.com/moocss/apiserver/src/model"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/moocss/apiserver/src/util"
+)
+
+type CreateRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type AssignRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// sendRoleError responds with the role error, hiding the database errors.
+func sendRoleError(c *gin.Context, err error) {
+	var e *errno.Errno
+	if errors.As(err, &e) {
+		util.SendResponse(c, err, nil)
+		return
+	}
+	util.SendResponse(c, errno.ErrDatabase, nil)
+}
+
+// @Summary List the roles
+// @Description List the roles with their permissions
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.RoleModel "{"code":0,"message":"OK","data":[{"name":"admin","description":"","permissions":[{"permission":"*"}]}]}"
+// @Router /admin/roles [get]
+func List(c *gin.Context) {
+	roles, err := service.Role.ListRoles()
+	if err != nil {
+		util.SendResponse(c, errno.ErrDatabase, nil)
+		return
+	}
+
+	util.SendResponse(c, nil, roles)
+}
+
+// @Summary Add new role to the database
+// @Description Add a new role with its permissions
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Param role body role.CreateRequest true "Create a new role"
+// @Success 200 {object} util.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/roles [post]
+func Create(c *gin.Context) {
+	log.Info("Role Create function called.", lager.Data{"X-Request-Id": util.GetReqID(c)})
+	var r CreateRequest
+	if err := c.Bind(&r); err != nil {
+		util.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	role := model.RoleModel{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: make([]model.RolePermissionModel, 0, len(r.Permissions)),
+	}
+	for _, p := range r.Permissions {
+		role.Permissions = append(role.Permissions, model.RolePermissionModel{Permission: p})
+	}
+
+	// Validate the data.
+	if err := service.Validate(&role); err != nil {
+		util.SendResponse(c, errno.New(errno.ErrValidation, err), nil)
+		return
+	}
+
+	if err := service.Role.CreateRole(&role); err != nil {
+		sendRoleError(c, err)
+		return
+	}
+
+	util.SendResponse(c, nil, nil)
+}
+
+// @Summary Get the roles of an user
+// @Description Get the roles assigned to the user
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "The user's database id index num"
+// @Success 200 {object} model.RoleModel "{"code":0,"message":"OK","data":[{"name":"admin","description":"","permissions":[{"permission":"*"}]}]}"
+// @Router /admin/users/{id}/roles [get]
+func GetUserRoles(c *gin.Context) {
+	userId, _ := strconv.Atoi(c.Param("id"))
+
+	roles, err := service.Role.GetUserRoles(uint64(userId))
+	if err != nil {
+		util.SendResponse(c, errno.ErrDatabase, nil)
+		return
+	}
+
+	util.SendResponse(c, nil, roles)
+}
+
+// @Summary Assign a role to an user
+// @Description Assign the role to the user
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "The user's database id index num"
+// @Param role body role.AssignRequest true "The role name"
+// @Success 200 {object} util.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/users/{id}/roles [post]
+func Assign(c *gin.Context) {
+	log.Info("Role Assign function called.", lager.Data{"X-Request-Id": util.GetReqID(c)})
+	userId, _ := strconv.Atoi(c.Param("id"))
+
+	var r AssignRequest
+	if err := c.Bind(&r); err != nil {
+		util.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	if service.User.WithContext(c.Request.Context()).GetUser(uint64(userId)) == nil {
+		util.SendResponse(c, errno.ErrUserNotFound, nil)
+		return
+	}
+
+	if err := service.Role.AssignRole(uint64(userId), r.Role); err != nil {
+		sendRoleError(c, err)
+		return
+	}
+
+	util.SendResponse(c, nil, nil)
+}
+
+// @Summary Revoke a role from an user
+// @Description Remove the role from the user
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "The user's database id index num"
+// @Param role path string true "The role name"
+// @Success 200 {object} util.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/users/{id}/roles/{role} [delete]
+func Revoke(c *gin.Context) {
+	log.Info("Role Revoke function called.", lager.Data{"X-Request-Id": util.GetReqID(c)})
+	userId, _ := strconv.Atoi(c.Param("id"))
+
+	if err := service.Role.RevokeRole(uint64(userId), c.Param("role")); err != nil {
+		sendRoleError(c, err)
+		return
+	}
+
+	util.SendResponse(c, nil, nil)
+}
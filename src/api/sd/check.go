@@ -1,10 +1,18 @@
 package sd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lexkong/log"
+	"github.com/lexkong/log/lager"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/pkg/health"
+	"github.com/moocss/apiserver/src/pkg/shutdown"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/disk"
 	"github.com/shirou/gopsutil/load"
@@ -18,82 +26,195 @@ const (
 	GB                    // 1 << (10 * 3)
 )
 
-// HealthCheck shows `OK` as the ping-pong result.
+func init() {
+	health.Register("shutdown", health.CheckerFunc(func(ctx context.Context) error {
+		if shutdown.Draining() {
+			return errors.New("draining")
+		}
+		return nil
+	}))
+}
+
+// Live reports the process is up, it doesn't check the dependencies.
+func Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Ready runs the registered checks, e.g. the database and the migrations,
+// it fails with 503 if any of them fails or the shutdown has started.
+func Ready(c *gin.Context) {
+	report := health.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// HealthCheck shows `OK` as the ping-pong result, it fails once the shutdown has started.
 func HealthCheck(c *gin.Context) {
+	if shutdown.Draining() {
+		c.String(http.StatusServiceUnavailable, "\nDRAINING")
+		return
+	}
+
 	message := "OK"
 	c.String(http.StatusOK, "\n"+message)
 }
 
-// DiskCheck checks the disk usage.
-func DiskCheck(c *gin.Context) {
-	u, _ := disk.Usage("/")
+// TLS 证书剩余的有效天数
+const (
+	tlsWarningDays  = 30
+	tlsCriticalDays = 7
+)
 
-	usedMB := int(u.Used) / MB
-	usedGB := int(u.Used) / GB
-	totalMB := int(u.Total) / MB
-	totalGB := int(u.Total) / GB
-	usedPercent := int(u.UsedPercent)
+// settings returns the thresholds of the current config.
+func settings(c *gin.Context, check string) *config.SectionSD {
+	conf := config.Current()
+	if conf == nil {
+		render(c, newResult(check, unknown("config", errors.New("the config isn't loaded"))))
+		return nil
+	}
+	return &conf.SD
+}
 
-	status := http.StatusOK
-	text := "OK"
-
-	if usedPercent >= 95 {
-		status = http.StatusOK
-		text = "CRITICAL"
-	} else if usedPercent >= 90 {
-		status = http.StatusTooManyRequests
-		text = "WARNING"
+// DiskCheck checks the disk usage of the mount points.
+func DiskCheck(c *gin.Context) {
+	sd := settings(c, "DISK")
+	if sd == nil {
+		return
 	}
 
-	message := fmt.Sprintf("%s - Free space: %dMB (%dGB) / %dMB (%dGB) | Used: %d%%", text, usedMB, usedGB, totalMB, totalGB, usedPercent)
-	c.String(status, "\n"+message)
+	metrics := make([]Metric, 0, len(sd.Disk.Mounts))
+	for _, mount := range sd.Disk.Mounts {
+		u, err := disk.Usage(mount)
+		if err != nil {
+			log.Error("Get the disk usage failed.", err, lager.Data{"mount": mount})
+			metrics = append(metrics, unknown(mount, err))
+			continue
+		}
+
+		metrics = append(metrics, Metric{
+			Name:     mount,
+			Status:   level(u.UsedPercent, sd.Disk.Warning, sd.Disk.Critical),
+			Value:    u.UsedPercent,
+			Unit:     "%",
+			Warning:  sd.Disk.Warning,
+			Critical: sd.Disk.Critical,
+			Max:      100,
+			Message: fmt.Sprintf("%d%% used, %dMB (%dGB) / %dMB (%dGB)", int(u.UsedPercent),
+				u.Used/MB, u.Used/GB, u.Total/MB, u.Total/GB),
+		})
+	}
+
+	render(c, newResult("DISK", metrics...))
 }
 
-// CPUCheck checks the cpu usage.
+// CPUCheck checks the 5 minutes load average per core.
 func CPUCheck(c *gin.Context) {
-	cores, _ := cpu.Counts(false)
+	sd := settings(c, "CPU")
+	if sd == nil {
+		return
+	}
 
-	a, _ := load.Avg()
-	l1 := a.Load1
-	l5 := a.Load5
-	l15 := a.Load15
+	cores, err := cpu.Counts(false)
+	if err == nil && cores == 0 {
+		// The physical cores are unknown in some containers.
+		cores, err = cpu.Counts(true)
+	}
+	if err == nil && cores == 0 {
+		err = errors.New("no cpu found")
+	}
+	if err != nil {
+		log.Error("Get the cpu count failed.", err)
+		render(c, newResult("CPU", unknown("load5", err)))
+		return
+	}
 
-	status := http.StatusOK
-	text := "OK"
-
-	if l5 >= float64(cores-1) {
-		status = http.StatusInternalServerError
-		text = "CRITICAL"
-	} else if l5 >= float64(cores-2) {
-		status = http.StatusTooManyRequests
-		text = "WARNING"
+	a, err := load.Avg()
+	if err != nil {
+		log.Error("Get the load average failed.", err)
+		render(c, newResult("CPU", unknown("load5", err)))
+		return
 	}
 
-	message := fmt.Sprintf("%s - Load average: %.2f, %.2f, %.2f | Cores: %d", text, l1, l5, l15, cores)
-	c.String(status, "\n"+message)
+	n := float64(cores)
+	render(c, newResult("CPU", Metric{
+		Name:     "load5",
+		Status:   level(a.Load5/n, sd.CPU.Warning, sd.CPU.Critical),
+		Value:    a.Load5,
+		Warning:  sd.CPU.Warning * n,
+		Critical: sd.CPU.Critical * n,
+		Message:  fmt.Sprintf("load average %.2f, %.2f, %.2f, %d cores", a.Load1, a.Load5, a.Load15, cores),
+	}))
 }
 
-// RAMCheck checks the disk usage.
+// RAMCheck checks the memory usage.
 func RAMCheck(c *gin.Context) {
-	u, _ := mem.VirtualMemory()
+	sd := settings(c, "RAM")
+	if sd == nil {
+		return
+	}
 
-	usedMB := int(u.Used) / MB
-	usedGB := int(u.Used) / GB
-	totalMB := int(u.Total) / MB
-	totalGB := int(u.Total) / GB
-	usedPercent := int(u.UsedPercent)
+	u, err := mem.VirtualMemory()
+	if err != nil {
+		log.Error("Get the memory usage failed.", err)
+		render(c, newResult("RAM", unknown("memory", err)))
+		return
+	}
 
-	status := http.StatusOK
-	text := "OK"
-
-	if usedPercent >= 95 {
-		status = http.StatusInternalServerError
-		text = "CRITICAL"
-	} else if usedPercent >= 90 {
-		status = http.StatusTooManyRequests
-		text = "WARNING"
+	render(c, newResult("RAM", Metric{
+		Name:     "memory",
+		Status:   level(u.UsedPercent, sd.RAM.Warning, sd.RAM.Critical),
+		Value:    u.UsedPercent,
+		Unit:     "%",
+		Warning:  sd.RAM.Warning,
+		Critical: sd.RAM.Critical,
+		Max:      100,
+		Message: fmt.Sprintf("%d%% used, %dMB (%dGB) / %dMB (%dGB)", int(u.UsedPercent),
+			u.Used/MB, u.Used/GB, u.Total/MB, u.Total/GB),
+	}))
+}
+
+// certificate is the TLS certificate served by the https listener.
+var certificate interface {
+	NotAfter() time.Time
+}
+
+// SetCertificate sets the certificate reported by TLSCheck.
+func SetCertificate(c interface{ NotAfter() time.Time }) {
+	certificate = c
+}
+
+// TLSCheck checks the days until the TLS certificate expires.
+func TLSCheck(c *gin.Context) {
+	if certificate == nil {
+		r := newResult("TLS", unknown("expiry", errors.New("TLS certificate is not loaded")))
+		if c.Query("format") == "json" {
+			c.JSON(http.StatusNotFound, r)
+			return
+		}
+		c.String(http.StatusNotFound, r.Nagios())
+		return
+	}
+
+	notAfter := certificate.NotAfter()
+	days := int(time.Until(notAfter).Hours() / 24)
+
+	status := StatusOK
+	if days < tlsCriticalDays {
+		status = StatusCritical
+	} else if days < tlsWarningDays {
+		status = StatusWarning
 	}
 
-	message := fmt.Sprintf("%s - Free space: %dMB (%dGB) / %dMB (%dGB) | Used: %d%%", text, usedMB, usedGB, totalMB, totalGB, usedPercent)
-	c.String(status, "\n"+message)
+	render(c, newResult("TLS", Metric{
+		Name:     "expiry",
+		Status:   status,
+		Value:    float64(days),
+		Warning:  tlsWarningDays,
+		Critical: tlsCriticalDays,
+		Message:  fmt.Sprintf("expires in %d days, not after %s", days, notAfter.Format(time.RFC3339)),
+	}))
 }
@@ -0,0 +1,130 @@
+package sd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The states of the checks, the same as the Nagios plugins.
+const (
+	StatusOK       = "OK"
+	StatusWarning  = "WARNING"
+	StatusCritical = "CRITICAL"
+	StatusUnknown  = "UNKNOWN"
+)
+
+// severity orders the states from the best to the worst.
+var severity = map[string]int{
+	StatusOK:       0,
+	StatusWarning:  1,
+	StatusUnknown:  2,
+	StatusCritical: 3,
+}
+
+// Metric is a value checked against the thresholds.
+type Metric struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Value    float64 `json:"value"`
+	Unit     string  `json:"unit"`
+	Warning  float64 `json:"warning"`
+	Critical float64 `json:"critical"`
+	Max      float64 `json:"max,omitempty"`
+	Message  string  `json:"message"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Result is the result of a check, its status is the worst of the metrics.
+type Result struct {
+	Check   string   `json:"check"`
+	Status  string   `json:"status"`
+	Metrics []Metric `json:"metrics"`
+}
+
+// level returns the status of the value, the higher the worse.
+func level(value, warning, critical float64) string {
+	switch {
+	case value >= critical:
+		return StatusCritical
+	case value >= warning:
+		return StatusWarning
+	}
+	return StatusOK
+}
+
+// unknown returns the metric which failed to be collected.
+func unknown(name string, err error) Metric {
+	return Metric{
+		Name:    name,
+		Status:  StatusUnknown,
+		Message: "failed to collect",
+		Error:   err.Error(),
+	}
+}
+
+func newResult(check string, metrics ...Metric) *Result {
+	r := &Result{Check: check, Status: StatusOK, Metrics: metrics}
+	for _, m := range metrics {
+		if severity[m.Status] > severity[r.Status] {
+			r.Status = m.Status
+		}
+	}
+	return r
+}
+
+// httpStatus maps the status of the check to the response code.
+func httpStatus(status string) int {
+	switch status {
+	case StatusWarning:
+		return http.StatusTooManyRequests
+	case StatusCritical:
+		return http.StatusServiceUnavailable
+	case StatusUnknown:
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// Nagios returns the output of the Nagios plugins, e.g.
+// `DISK WARNING - /: 91% used (91GB / 100GB) | '/'=91%;90;95;0;100`.
+func (r *Result) Nagios() string {
+	messages := make([]string, 0, len(r.Metrics))
+	perfs := make([]string, 0, len(r.Metrics))
+	for _, m := range r.Metrics {
+		if m.Error != "" {
+			messages = append(messages, fmt.Sprintf("%s: %s: %s", m.Name, m.Message, m.Error))
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", m.Name, m.Message))
+
+		// The lower the worse if the warning is greater than the critical, e.g. the days until expiry.
+		format := "'%s'=%.2f%s;%g;%g;0;"
+		if m.Warning > m.Critical {
+			format = "'%s'=%.2f%s;%g:;%g:;0;"
+		}
+		perf := fmt.Sprintf(format, m.Name, m.Value, m.Unit, m.Warning, m.Critical)
+		if m.Max > 0 {
+			perf += fmt.Sprintf("%g", m.Max)
+		}
+		perfs = append(perfs, perf)
+	}
+
+	out := fmt.Sprintf("%s %s - %s", r.Check, r.Status, strings.Join(messages, ", "))
+	if len(perfs) > 0 {
+		out += " | " + strings.Join(perfs, " ")
+	}
+	return out
+}
+
+// render writes the result as JSON with `?format=json`, or as the Nagios plugin output.
+func render(c *gin.Context, r *Result) {
+	status := httpStatus(r.Status)
+	if c.Query("format") == "json" {
+		c.JSON(status, r)
+		return
+	}
+	c.String(status, r.Nagios())
+}
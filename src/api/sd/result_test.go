@@ -0,0 +1,28 @@
+package sd
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResult(t *testing.T) {
+	r := newResult("DISK",
+		Metric{Name: "/", Status: level(91, 90, 95), Value: 91, Unit: "%", Warning: 90, Critical: 95, Max: 100, Message: "91% used"},
+		Metric{Name: "/data", Status: level(40, 90, 95), Value: 40, Unit: "%", Warning: 90, Critical: 95, Max: 100, Message: "40% used"},
+	)
+	assert.Equal(t, StatusWarning, r.Status)
+	assert.Equal(t, http.StatusTooManyRequests, httpStatus(r.Status))
+	assert.Equal(t, "DISK WARNING - /: 91% used, /data: 40% used | '/'=91.00%;90;95;0;100 '/data'=40.00%;90;95;0;100", r.Nagios())
+
+	// The metric failed to be collected.
+	r = newResult("DISK", r.Metrics[0], unknown("/mnt", errors.New("no such file or directory")))
+	assert.Equal(t, StatusUnknown, r.Status)
+	assert.Equal(t, http.StatusInternalServerError, httpStatus(r.Status))
+
+	r = newResult("DISK", Metric{Name: "/", Status: level(96, 90, 95)}, r.Metrics[1])
+	assert.Equal(t, StatusCritical, r.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, httpStatus(r.Status))
+}
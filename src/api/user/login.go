@@ -0,0 +1,73 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/pkg/auth"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/pkg/token"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/moocss/apiserver/src/util"
+)
+
+// dummyHash is compared with the password of the unknown users, the response takes as long as for a wrong password.
+const dummyHash = "$2a$10$zJmBCWCjLJr.YB4bBw7rNOAskwqVLcv0DwD8KRSV4FI9.QnxK.9Pi"
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type LoginResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	SessionID    string `json:"session_id"`
+}
+
+// @Summary Login generates the authentication token
+// @Description Login with username and password
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param user body user.LoginRequest true "Username and password"
+// @Success 200 {object} user.LoginResponse "{"code":0,"message":"OK","data":{"token":"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...","refresh_token":"3q2-7w...","session_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}}"
+// @Router /login [post]
+func (h *Handler) Login(c *gin.Context) {
+	util.Logger(c).Info("User Login function called.")
+	// Binding the data with the user struct.
+	var r LoginRequest
+	if err := c.Bind(&r); err != nil {
+		util.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	// Get the user information by the login username.
+	// The unknown username and the wrong password get the same error, not to reveal the existing users.
+	u := h.srv.WithContext(c.Request.Context()).GetUserByName(r.Username)
+	if u == nil {
+		auth.Compare(dummyHash, r.Password)
+		util.SendResponse(c, errno.ErrCredentialsInvalid, nil)
+		return
+	}
+
+	// Compare the login password with the user password.
+	if err := h.srv.Compare(u, r.Password); err != nil {
+		util.SendResponse(c, errno.ErrCredentialsInvalid, nil)
+		return
+	}
+
+	// Sign the json web token.
+	t, err := token.Sign(token.Context{ID: u.ID, Username: u.Username}, "")
+	if err != nil {
+		util.SendResponse(c, errno.ErrToken, nil)
+		return
+	}
+
+	// Start a new session for the refresh token.
+	session, rt, err := service.Token.IssueRefreshToken(u.ID)
+	if err != nil {
+		util.SendResponse(c, errno.ErrDatabase, nil)
+		return
+	}
+
+	util.SendResponse(c, nil, LoginResponse{Token: t, RefreshToken: rt, SessionID: session.Family})
+}
@@ -0,0 +1,121 @@
+package user
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/pkg/token"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/moocss/apiserver/src/util"
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// sendTokenError responds with the token error, hiding the database errors.
+func sendTokenError(c *gin.Context, err error) {
+	var e *errno.Errno
+	if errors.As(err, &e) {
+		util.SendResponse(c, err, nil)
+		return
+	}
+	util.SendResponse(c, errno.ErrDatabase, nil)
+}
+
+// @Summary Refresh the authentication token
+// @Description Exchange the refresh token for a new token pair, the refresh token can be used only once
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param token body user.RefreshRequest true "The refresh token"
+// @Success 200 {object} user.LoginResponse "{"code":0,"message":"OK","data":{"token":"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...","refresh_token":"3q2-7w...","session_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}}"
+// @Router /token/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	util.Logger(c).Info("Token Refresh function called.")
+	var r RefreshRequest
+	if err := c.Bind(&r); err != nil {
+		util.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	// Rotate the refresh token.
+	t, refreshToken, err := service.Token.RotateRefreshToken(r.RefreshToken)
+	if err != nil {
+		sendTokenError(c, err)
+		return
+	}
+
+	u := h.srv.WithContext(c.Request.Context()).GetUser(t.UserID)
+	if u == nil {
+		util.SendResponse(c, errno.ErrUserNotFound, nil)
+		return
+	}
+
+	// Sign the json web token.
+	tokenString, err := token.Sign(token.Context{ID: u.ID, Username: u.Username}, "")
+	if err != nil {
+		util.SendResponse(c, errno.ErrToken, nil)
+		return
+	}
+
+	util.SendResponse(c, nil, LoginResponse{Token: tokenString, RefreshToken: refreshToken, SessionID: t.Family})
+}
+
+// @Summary Logout revokes the session of the refresh token
+// @Description Revoke the refresh token and all the tokens rotated from it
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param token body user.RefreshRequest true "The refresh token"
+// @Success 200 {object} util.Response "{"code":0,"message":"OK","data":null}"
+// @Router /logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	util.Logger(c).Info("User Logout function called.")
+	var r RefreshRequest
+	if err := c.Bind(&r); err != nil {
+		util.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	if err := service.Token.RevokeRefreshToken(util.GetUserID(c), r.RefreshToken); err != nil {
+		sendTokenError(c, err)
+		return
+	}
+
+	util.SendResponse(c, nil, nil)
+}
+
+// @Summary List the sessions of the current user
+// @Description List the active refresh token families, the id is used to revoke the session
+// @Tags user
+// @Produce  json
+// @Success 200 {object} util.Response "{"code":0,"message":"OK","data":[{"id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","createdAt":"2020-03-17T16:25:33+08:00","expiresAt":"2020-04-16T16:25:33+08:00"}]}"
+// @Router /sessions [get]
+func (h *Handler) ListSessions(c *gin.Context) {
+	sessions, err := service.Token.ListSessions(util.GetUserID(c))
+	if err != nil {
+		util.SendResponse(c, errno.ErrDatabase, nil)
+		return
+	}
+
+	util.SendResponse(c, nil, sessions)
+}
+
+// @Summary Revoke a session by the session identifier
+// @Description Revoke the refresh token family of the current user
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param id path string true "The session (refresh token family) id"
+// @Success 200 {object} util.Response "{"code":0,"message":"OK","data":null}"
+// @Router /sessions/{id} [delete]
+func (h *Handler) DeleteSession(c *gin.Context) {
+	if err := service.Token.RevokeSession(util.GetUserID(c), c.Param("id")); err != nil {
+		sendTokenError(c, err)
+		return
+	}
+
+	util.SendResponse(c, nil, nil)
+}
@@ -1,16 +1,25 @@
 package user
 
 import (
+	"errors"
 	"github.com/moocss/apiserver/src/model"
 	"github.com/moocss/apiserver/src/service"
 	"github.com/gin-gonic/gin"
 	"github.com/moocss/apiserver/src/pkg/errno"
 	"github.com/moocss/apiserver/src/util"
-	"github.com/lexkong/log"
-	"github.com/lexkong/log/lager"
 	"strconv"
 )
 
+// Handler handles the user APIs with the injected user service.
+type Handler struct {
+	srv *service.UserService
+}
+
+// NewHandler returns the user handler using the user service.
+func NewHandler(srv *service.UserService) *Handler {
+	return &Handler{srv: srv}
+}
+
 type CreateRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -21,9 +30,9 @@ type CreateResponse struct {
 }
 
 type ListRequest struct {
-	Username string `json:"username"`
-	Offset   int    `json:"offset"`
-	Limit    int    `json:"limit"`
+	Username string `json:"username" form:"username"`
+	Offset   int    `json:"offset" form:"offset"`
+	Limit    int    `json:"limit" form:"limit"`
 }
 
 type ListResponse struct {
@@ -42,15 +51,15 @@ type SwaggerListResponse struct {
 // @Accept  json
 // @Produce  json
 // @Param username path string true "Username"
-// @Success 200 {object} model.UserModel "{"code":0,"message":"OK","data":{"username":"kong","password":"$2a$10$E0kwtmtLZbwW/bDQ8qI8e.eHPqhQOW9tvjwpyo/p05f/f4Qvr3OmS"}}"
+// @Success 200 {object} model.UserResult "{"code":0,"message":"OK","data":{"id":1,"username":"kong","createdAt":"2018-05-27T16:25:33+08:00","updatedAt":"2018-05-27T16:25:33+08:00"}}"
 // @Router /user/{username} [get]
-func Get(c *gin.Context) {
+func (h *Handler) Get(c *gin.Context) {
 	username := c.Param("username")
 	// Get the user by the `username` from the database.
-	user :=  service.User.GetUserByName(username)
+	user :=  h.srv.WithContext(c.Request.Context()).GetUserByName(username)
 
 	if user != nil {
-		util.SendResponse(c, nil, user)
+		util.SendResponse(c, nil, user.Result())
 		return
 	}
 
@@ -59,6 +68,41 @@ func Get(c *gin.Context) {
 }
 
 
+// @Summary List the users in the database
+// @Description List users
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param username query string false "Filter by the username"
+// @Param offset query int false "The offset of the first user"
+// @Param limit query int false "The max number of users, default 20, up to 100"
+// @Success 200 {object} user.SwaggerListResponse "{"code":0,"message":"OK","data":{"totalCount":1,"userList":[{"id":1,"username":"admin","createdAt":"2018-05-27T16:25:33+08:00","updatedAt":"2018-05-27T16:25:33+08:00"}]}}"
+// @Router /user [get]
+func (h *Handler) List(c *gin.Context) {
+	var r ListRequest
+	if err := c.Bind(&r); err != nil {
+		util.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	users, count, err := h.srv.WithContext(c.Request.Context()).GetUserList(r.Username, r.Offset, r.Limit)
+	if err != nil {
+		util.SendResponse(c, errno.ErrDatabase, nil)
+		return
+	}
+
+	list := make([]*model.UserResult, 0, len(users))
+	for _, u := range users {
+		list = append(list, u.Result())
+	}
+
+	util.SendResponse(c, nil, ListResponse{
+		TotalCount: count,
+		UserList:   list,
+	})
+}
+
+
 // @Summary Add new user to the database
 // @Description Add a new user
 // @Tags user
@@ -67,8 +111,8 @@ func Get(c *gin.Context) {
 // @Param user body user.CreateRequest true "Create a new user"
 // @Success 200 {object} user.CreateResponse "{"code":0,"message":"OK","data":{"username":"kong"}}"
 // @Router /user [post]
-func Create(c *gin.Context) {
-	log.Info("User Create function called.", lager.Data{"X-Request-Id": util.GetReqID(c)})
+func (h *Handler) Create(c *gin.Context) {
+	util.Logger(c).Info("User Create function called.")
 	var r CreateRequest
 	if err := c.Bind(&r); err != nil {
 		util.SendResponse(c, errno.ErrBind, nil)
@@ -81,18 +125,18 @@ func Create(c *gin.Context) {
 	}
 
 	// Validate the data.
-	if err := service.User.Validate(&u); err != nil {
-		util.SendResponse(c, errno.ErrValidation, nil)
+	if err := h.srv.Validate(&u); err != nil {
+		util.SendResponse(c, errno.New(errno.ErrValidation, err), nil)
 		return
 	}
 
 	// Encrypt the user password.
-	if err := service.User.Encrypt(&u); err != nil {
+	if err := h.srv.Encrypt(&u); err != nil {
 		util.SendResponse(c, errno.ErrEncrypt, nil)
 		return
 	}
 	// Insert the user to the database.
-	if err := service.User.CreateUser(&u); err != nil {
+	if err := h.srv.WithContext(c.Request.Context()).CreateUser(&u); err != nil {
 		util.SendResponse(c, errno.ErrDatabase, nil)
 		return
 	}
@@ -114,9 +158,13 @@ func Create(c *gin.Context) {
 // @Param id path uint64 true "The user's database id index num"
 // @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
 // @Router /user/{id} [delete]
-func Delete(c *gin.Context) {
-	userId, _ := strconv.Atoi(c.Param("id"))
-	if err := service.User.DeleteUser(uint64(userId)); err != nil {
+func (h *Handler) Delete(c *gin.Context) {
+	userId, err := userID(c)
+	if err != nil {
+		util.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if err := h.srv.WithContext(c.Request.Context()).DeleteUser(userId); err != nil {
 		util.SendResponse(c, errno.ErrDatabase, nil)
 		return
 	}
@@ -134,38 +182,61 @@ func Delete(c *gin.Context) {
 // @Param user body model.UserModel true "The user info"
 // @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
 // @Router /user/{id} [put]
-func Update(c *gin.Context) {
-	log.Info("Update function called.", lager.Data{"X-Request-Id": util.GetReqID(c)})
+func (h *Handler) Update(c *gin.Context) {
+	util.Logger(c).Info("Update function called.")
 	// Get the user id from the url parameter.
-	userId, _ := strconv.Atoi(c.Param("id"))
+	userId, err := userID(c)
+	if err != nil {
+		util.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
 
 	// Binding the user data.
-	var u model.UserModel
-	if err := c.Bind(&u); err != nil {
+	var r model.UserModel
+	if err := c.Bind(&r); err != nil {
 		util.SendResponse(c, errno.ErrBind, nil)
 		return
 	}
 
 	// We update the record based on the user id.
-	u.ID = uint64(userId)
+	srv := h.srv.WithContext(c.Request.Context())
+	u := srv.GetUser(userId)
+	if u == nil {
+		util.SendResponse(c, errno.ErrUserNotFound, nil)
+		return
+	}
+	u.Username = r.Username
+	u.Password = r.Password
 
 	// Validate the data.
-	if err := service.User.Validate(&u); err != nil {
-		util.SendResponse(c, errno.ErrValidation, nil)
+	if err := h.srv.Validate(u); err != nil {
+		util.SendResponse(c, errno.New(errno.ErrValidation, err), nil)
 		return
 	}
 
 	// Encrypt the user password.
-	if err := service.User.Encrypt(&u); err != nil {
+	if err := h.srv.Encrypt(u); err != nil {
 		util.SendResponse(c, errno.ErrEncrypt, nil)
 		return
 	}
 
 	// Save changed fields.
-	if err :=  service.User.UpdateUser(&u); err != nil {
+	if err :=  srv.UpdateUser(u); err != nil {
 		util.SendResponse(c, errno.ErrDatabase, nil)
 		return
 	}
 
 	util.SendResponse(c, nil, nil)
 }
+
+// userID returns the `id` parameter of the url, the id 0 is invalid.
+func userID(c *gin.Context) (uint64, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		return 0, errors.New("the user id can't be 0")
+	}
+	return id, nil
+}
@@ -0,0 +1,171 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/moocss/apiserver/src/util"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	h := NewHandler(service.NewUserService(service.NewMemoryUserRepository()))
+	g := gin.New()
+	g.POST("/v1/user", h.Create)
+	g.GET("/v1/user", h.List)
+	g.GET("/v1/user/:username", h.Get)
+	g.PUT("/v1/user/:id", h.Update)
+	g.DELETE("/v1/user/:id", h.Delete)
+	g.POST("/v1/login", h.Login)
+	return g
+}
+
+func doRequest(g *gin.Engine, method, path string, body interface{}) util.Response {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+
+	var rsp util.Response
+	json.Unmarshal(w.Body.Bytes(), &rsp)
+	return rsp
+}
+
+func TestCreateAndGet(t *testing.T) {
+	g := newTestRouter()
+
+	rsp := doRequest(g, "POST", "/v1/user", CreateRequest{Username: "kong", Password: "kong123"})
+	assert.Equal(t, errno.OK.Code, rsp.Code)
+
+	rsp = doRequest(g, "POST", "/v1/user", CreateRequest{Username: "kong", Password: "kong"})
+	assert.Equal(t, errno.ErrValidation.Code, rsp.Code)
+
+	rsp = doRequest(g, "GET", "/v1/user/kong", nil)
+	assert.Equal(t, errno.OK.Code, rsp.Code)
+	assert.NotContains(t, rsp.Data, "password")
+
+	rsp = doRequest(g, "GET", "/v1/user/nobody", nil)
+	assert.Equal(t, errno.ErrUserNotFound.Code, rsp.Code)
+
+	rsp = doRequest(g, "GET", "/v1/user?username=ko&limit=10", nil)
+	assert.Equal(t, errno.OK.Code, rsp.Code)
+	assert.Equal(t, float64(1), rsp.Data.(map[string]interface{})["totalCount"])
+}
+
+func TestInvalidID(t *testing.T) {
+	g := newTestRouter()
+	doRequest(g, "POST", "/v1/user", CreateRequest{Username: "kong", Password: "kong123"})
+
+	for _, id := range []string{"abc", "0", "-1"} {
+		rsp := doRequest(g, "DELETE", "/v1/user/"+id, nil)
+		assert.Equal(t, errno.ErrBind.Code, rsp.Code, id)
+
+		rsp = doRequest(g, "PUT", "/v1/user/"+id, CreateRequest{Username: "kong", Password: "kong456"})
+		assert.Equal(t, errno.ErrBind.Code, rsp.Code, id)
+	}
+
+	rsp := doRequest(g, "GET", "/v1/user/kong", nil)
+	assert.Equal(t, errno.OK.Code, rsp.Code)
+}
+
+func TestHTTPStatus(t *testing.T) {
+	g := newTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/user/nobody", nil)
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/v1/user", bytes.NewBufferString("{"))
+	req.Header.Set("Content-Type", "application/json")
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestValidationErrors(t *testing.T) {
+	g := newTestRouter()
+
+	rsp := doRequest(g, "POST", "/v1/user", CreateRequest{Username: "kong", Password: "kong"})
+	assert.Equal(t, errno.ErrValidation.Code, rsp.Code)
+	assert.Equal(t, []util.FieldError{{Field: "password", Rule: "min", Param: "5", Message: "password must be at least 5 characters"}}, rsp.Errors)
+
+	req, _ := http.NewRequest("POST", "/v1/user", bytes.NewBufferString(`{"username":"kong","password":"kong"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", util.ProblemContentType)
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+
+	var problem util.Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, util.ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, errno.ErrValidation.Code, problem.Code)
+	assert.Equal(t, "/v1/user", problem.Instance)
+	assert.Equal(t, []util.FieldError{{Field: "password", Rule: "min", Param: "5", Message: "password must be at least 5 characters"}}, problem.Errors)
+}
+
+func TestLocalizedMessages(t *testing.T) {
+	g := newTestRouter()
+
+	req, _ := http.NewRequest("POST", "/v1/user", bytes.NewBufferString(`{"username":"kong","password":"kong"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "fr;q=1, zh;q=0.8, en;q=0.5")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+
+	var rsp util.Response
+	json.Unmarshal(w.Body.Bytes(), &rsp)
+	assert.Equal(t, "zh-CN", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+	assert.Equal(t, "参数校验失败。", rsp.Message)
+	assert.Equal(t, "password的长度不能少于5个字符", rsp.Errors[0].Message)
+
+	// The lang query parameter overrides the header.
+	req, _ = http.NewRequest("GET", "/v1/user/nobody?lang=en", nil)
+	req.Header.Set("Accept-Language", "zh-CN")
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &rsp)
+	assert.Equal(t, errno.ErrUserNotFound.Message, rsp.Message)
+}
+
+func TestLoginFailure(t *testing.T) {
+	g := newTestRouter()
+	doRequest(g, "POST", "/v1/user", CreateRequest{Username: "kong", Password: "kong123"})
+
+	// The unknown user and the wrong password can't be told apart.
+	for _, r := range []LoginRequest{{Username: "nobody", Password: "kong123"}, {Username: "kong", Password: "wrong"}} {
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(r)
+		req, _ := http.NewRequest("POST", "/v1/login", &buf)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+
+		var rsp util.Response
+		json.Unmarshal(w.Body.Bytes(), &rsp)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, errno.ErrCredentialsInvalid.Code, rsp.Code)
+	}
+
+	// The unknown users pay the same bcrypt cost.
+	cost, err := bcrypt.Cost([]byte(dummyHash))
+	assert.NoError(t, err)
+	assert.Equal(t, bcrypt.DefaultCost, cost)
+}
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,7 +13,7 @@ import (
 // Test file is missing
 func TestMissingFile(t *testing.T) {
 	filename := "test"
-	_, err := Init(filename)
+	_, err := Init(filename, "")
 
 	assert.NotNil(t, err)
 }
@@ -23,11 +26,11 @@ type ConfigTestSuite struct {
 
 func (suite *ConfigTestSuite) SetupTest() {
 	var err error
-	suite.ConfDefault, err = Init("")
+	suite.ConfDefault, err = Init("", "")
 	if err != nil {
 		panic("failed to load default config.yml")
 	}
-	suite.Conf, err = Init("src/config/config")
+	suite.Conf, err = Init("config.yaml", "")
 	if err != nil {
 		panic("failed to load config.yml from file")
 	}
@@ -43,8 +46,8 @@ func (suite *ConfigTestSuite) TestValidateConfDefault() {
 	assert.Equal(suite.T(), 2, suite.ConfDefault.Core.MaxPingCount)
 	assert.Equal(suite.T(), "Rtg8BPKNEf2mB4mgvKONGPZZQSaJWNLijxR42qRgq0iBb5", suite.ConfDefault.Core.JwtSecret)
 	assert.Equal(suite.T(), "9098", suite.ConfDefault.Core.TLS.Port)
-	assert.Equal(suite.T(), "src/config/server.crt", suite.ConfDefault.Core.TLS.CertPath)
-	assert.Equal(suite.T(), "src/config/server.key", suite.ConfDefault.Core.TLS.KeyPath)
+	assert.Equal(suite.T(), "", suite.ConfDefault.Core.TLS.CertPath)
+	assert.Equal(suite.T(), "", suite.ConfDefault.Core.TLS.KeyPath)
 
 	// Log
 	assert.Equal(suite.T(), "file,stdout", suite.ConfDefault.Log.Writers)
@@ -60,7 +63,7 @@ func (suite *ConfigTestSuite) TestValidateConfDefault() {
 	assert.Equal(suite.T(), "db_apiserver", suite.ConfDefault.Db.Name)
 	assert.Equal(suite.T(), "127.0.0.1:3306", suite.ConfDefault.Db.Addr)
 	assert.Equal(suite.T(), "root", suite.ConfDefault.Db.Username)
-	assert.Equal(suite.T(), "root", suite.ConfDefault.Db.Password)
+	assert.Equal(suite.T(), "123456", suite.ConfDefault.Db.Password)
 
 }
 
@@ -71,6 +74,177 @@ func (suite *ConfigTestSuite) TestValidateConf() {
 	assert.Equal(suite.T(), "debug", suite.Conf.Core.Mode)
 }
 
+func writeConfig(t *testing.T, dir, name, content string) string {
+	file := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func TestProfileMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	base := writeConfig(t, dir, "config.yaml", `
+core:
+  port: "8080"
+  jwt_secret: "base-secret"
+  tls:
+    port: "8443"
+    client_auth: "request"
+db:
+  name: "db_base"
+  addr: "10.0.0.1:3306"
+`)
+	writeConfig(t, dir, "config.prod.yaml", `
+core:
+  tls:
+    port: "443"
+db:
+  addr: "10.0.0.2:3306"
+`)
+	writeConfig(t, dir, "config.dev.yaml", `
+core:
+  mode: "test"
+`)
+
+	conf, err := Load(base, "prod")
+	assert.NoError(t, err)
+	// The overlay overrides the keys of the base file.
+	assert.Equal(t, "443", conf.Core.TLS.Port)
+	assert.Equal(t, "10.0.0.2:3306", conf.Db.Addr)
+	// The sibling keys of the nested sections are kept.
+	assert.Equal(t, "request", conf.Core.TLS.ClientAuth)
+	assert.Equal(t, "db_base", conf.Db.Name)
+	assert.Equal(t, "8080", conf.Core.Port)
+	// The keys of neither file use the defaults.
+	assert.Equal(t, "apiserver", conf.Core.Name)
+	// The mode defaults from the profile.
+	assert.Equal(t, "release", conf.Core.Mode)
+
+	// The mode of the config files overrides the profile one.
+	conf, err = Load(base, "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "test", conf.Core.Mode)
+	assert.Equal(t, "8443", conf.Core.TLS.Port)
+
+	// Without profile, only the base file is read.
+	conf, err = Load(base, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "debug", conf.Core.Mode)
+	assert.Equal(t, "10.0.0.1:3306", conf.Db.Addr)
+
+	// The profile from the environment.
+	os.Setenv("APISERVER_PROFILE", "prod")
+	conf, err = Load(base, "")
+	os.Unsetenv("APISERVER_PROFILE")
+	assert.NoError(t, err)
+	assert.Equal(t, "443", conf.Core.TLS.Port)
+
+	// The overlay of the profile must exist.
+	_, err = Load(base, "staging")
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	conf := loadConfigBytes(t, defaultConf)
+	assert.NoError(t, conf.Validate())
+
+	conf.Core.Mode = "release"
+	conf.Core.Port = "70000"
+	conf.Core.TLS.CertPath = "server.crt"
+	conf.Log.LoggerLevel = "VERBOSE"
+	conf.Db.Timezone = "Mars/Olympus"
+
+	err := conf.Validate()
+	assert.Error(t, err)
+	assert.Len(t, err.(ValidationErrors), 5)
+
+	// No listener.
+	conf = loadConfigBytes(t, defaultConf)
+	conf.Core.Port = ""
+	assert.Error(t, conf.Validate())
+
+	// The ACME challenges are answered on the port 80.
+	conf.Core.AutoTLS.Enabled = true
+	assert.Error(t, conf.Validate())
+	conf.Core.Port = "80"
+	assert.NoError(t, conf.Validate())
+
+	// The replicas need the address and the check interval.
+	conf.Db.Replicas = []SectionReplica{{Addr: "127.0.0.1:3307"}, {}}
+	conf.Db.ReplicaCheckInterval = 0
+	err = conf.Validate()
+	assert.Error(t, err)
+	assert.Len(t, err.(ValidationErrors), 2)
+
+	// tls_ca always verifies the server certificate.
+	conf = loadConfigBytes(t, defaultConf)
+	conf.Db.TLSCA = "ca.pem"
+	conf.Db.TLS = "false"
+	assert.Error(t, conf.Validate())
+	conf.Db.TLS = "true"
+	assert.NoError(t, conf.Validate())
+
+	// The plain http port can't bypass the required client certificates.
+	conf = loadConfigBytes(t, defaultConf)
+	conf.Core.TLS.CertPath = "server.crt"
+	conf.Core.TLS.KeyPath = "server.key"
+	conf.Core.TLS.ClientAuth = "require"
+	assert.Error(t, conf.Validate())
+	conf.Core.TLS.Redirect = true
+	assert.NoError(t, conf.Validate())
+	conf.Core.TLS.Redirect = false
+	conf.Core.Port = ""
+	assert.NoError(t, conf.Validate())
+}
+
+func TestLocalURL(t *testing.T) {
+	conf := loadConfigBytes(t, defaultConf)
+	assert.Equal(t, "http://localhost:9090/sd/live", conf.LocalURL("/sd/live"))
+
+	conf.Core.Address = "0.0.0.0"
+	assert.Equal(t, "http://localhost:9090/sd/live", conf.LocalURL("/sd/live"))
+
+	// The server only listens on the interface.
+	conf.Core.Address = "10.0.0.5"
+	assert.Equal(t, "http://10.0.0.5:9090/sd/live", conf.LocalURL("/sd/live"))
+	conf.Core.Address = "fd00::5"
+	assert.Equal(t, "http://[fd00::5]:9090/sd/live", conf.LocalURL("/sd/live"))
+
+	conf.Core.Port = ""
+	assert.Equal(t, "https://[fd00::5]:9098/sd/live", conf.LocalURL("/sd/live"))
+	conf.Core.AutoTLS.Enabled = true
+	assert.Equal(t, "https://[fd00::5]:443/sd/live", conf.LocalURL("/sd/live"))
+}
+
 func TestConfigTestSuite(t *testing.T) {
 	suite.Run(t, new(ConfigTestSuite))
 }
+
+func TestResolveSecrets(t *testing.T) {
+	file, err := ioutil.TempFile("", "db_password")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("s3cret\n")
+	file.Close()
+
+	os.Setenv("APISERVER_TEST_JWT_SECRET", "jwt-s3cret")
+	defer os.Unsetenv("APISERVER_TEST_JWT_SECRET")
+
+	conf := loadConfigBytes(t, defaultConf)
+	conf.Db.Password = "file://" + file.Name()
+	conf.Core.JwtSecret = "env:APISERVER_TEST_JWT_SECRET"
+	assert.NoError(t, resolveSecrets(&conf))
+	assert.Equal(t, "s3cret", conf.Db.Password)
+	assert.Equal(t, "jwt-s3cret", conf.Core.JwtSecret)
+
+	for _, s := range Settings(&conf) {
+		assert.NotContains(t, s.Value, "s3cret")
+	}
+
+	conf.Db.Replicas = []SectionReplica{{Addr: "127.0.0.1:3307", Password: "env:APISERVER_TEST_MISSING"}}
+	err = resolveSecrets(&conf)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "db.replicas.0.password")
+}
@@ -6,9 +6,15 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/lexkong/log"
 	"github.com/spf13/viper"
+	"net"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
+// defaultJwtSecret is the jwt secret of the defaultConf, it's refused in release mode.
+const defaultJwtSecret = "Rtg8BPKNEf2mB4mgvKONGPZZQSaJWNLijxR42qRgq0iBb5"
+
 var defaultConf = []byte(`
 core:
   enabled: true                   # enabale httpd server
@@ -17,13 +23,40 @@ core:
   address: ""                     # ip address to bind (default: any)
   port: "9090"                    # HTTP 绑定端口.
   max_ping_count: 2               # pingServer函数try的次数
-  jwt_secret: "Rtg8BPKNEf2mB4mgvKONGPZZQSaJWNLijxR42qRgq0iBb5"
+  always_ok: false                # 兼容旧客户端, 出错时也返回 HTTP 200, 错误只通过 code 区分
+  language: "en"                  # 错误信息的默认语言, en 或 zh-CN, 客户端可以通过 lang 参数或 Accept-Language 头指定
+  shutdown_timeout: "30s"         # 优雅退出时等待处理中的请求结束的最长时间
+  shutdown_delay: "5s"            # 优雅退出前健康检查先返回失败的时间, 让负载均衡摘除流量
+  jwt_secret: "Rtg8BPKNEf2mB4mgvKONGPZZQSaJWNLijxR42qRgq0iBb5"   # release 模式下必须修改, 支持 file:///run/secrets/jwt_secret 或 env:JWT_SECRET
+  jwt_timeout: "2h"               # JWT 的有效期
+  refresh_token_timeout: "720h"   # refresh token 的有效期
   tls:
     port: "9098"
     cert_path: ""                 # src/config/server.crt
     key_path: ""                  # src/config/server.key
+    redirect: false               # HTTP 端口的请求重定向到 HTTPS, /sd/* 和 ACME challenge 除外
+    client_auth: "none"           # 双向 TLS 客户端认证, none, request, require (需要 redirect 或关闭 HTTP 端口)
+    client_ca: ""                 # 校验客户端证书的 CA 证书
+    client_identity: "subject"    # 客户端证书映射的用户名, subject (CN) 或 san
+  cors:
+    allow_origins: ["*"]          # 允许跨域访问的 Origin, * 表示全部
+  rate_limit:
+    enabled: false                # 全局限流, /sd/* 除外
+    rps: 100                      # 每秒允许的请求数
+    burst: 200                    # 允许的突发请求数
+  hsts:
+    enabled: true                 # HTTPS 响应添加 Strict-Transport-Security 头
+    max_age: 31536000
+    include_subdomains: false
+    preload: false
+  access_log:
+    enabled: true                 # 每个请求输出一条结构化的访问日志
+    skip_paths: ["/sd/"]          # 不记录的路径前缀
+    body: false                   # debug 模式下记录请求和响应的 body, 密码等字段会被屏蔽
+    max_body_size: 4096           # 记录的 body 的最大字节数
+    mask_fields: ["password", "token", "secret"]   # 屏蔽名字包含这些词的字段
   auto_tls:
-    enabled: false                # Automatically install TLS certificates from Let's Encrypt.
+    enabled: false                # Automatically install TLS certificates from Let's Encrypt, core.port must be 80.
     folder: ".cache"              # folder for storing TLS certificates
     host: ""                      # which domains the Let's Encrypt will attempt
 
@@ -38,174 +71,329 @@ log:
   log_backup_count: 7                 # 当日志文件达到转存标准时，log 系统会将该日志文件进行压缩备份，这里指定了备份文件的最大个数
 
 db:
+  driver: "mysql"                     # 数据库驱动, mysql 或 sqlite3
+  path: "apiserver.db"                # sqlite3 的数据库文件
+  auto_migrate: false                 # 启动时执行未应用的数据库迁移, 也可以使用 apiserver migrate up
+  max_open_conns: 50                  # 最大打开的连接数, 0 表示不限制
+  max_idle_conns: 10                  # 最大闲置的连接数
+  conn_max_lifetime: "1h"             # 连接的最长使用时间, 0 表示不限制
+  conn_max_idle_time: "10m"           # 连接的最长闲置时间, 0 表示不限制
+  log_mode: false                     # 打印 SQL 日志
+  charset: "utf8mb4"                  # MySQL 连接的字符集
+  timezone: "Local"                   # 解析时间使用的时区, 例如 Local, UTC, Asia/Shanghai
+  tls: "false"                        # 连接 MySQL 使用 TLS, false, true, skip-verify, preferred
+  tls_ca: ""                          # 校验 MySQL 服务端证书的 CA 证书, 设置时 tls 必须为空或 true
+  timeout: "5s"                       # 建立连接的超时时间
+  read_timeout: "30s"                 # 读超时时间
+  write_timeout: "30s"                # 写超时时间
+  connect_max_wait: "60s"             # 启动时连接失败按指数退避重试的最长时间, 超时后启动失败
   name: "db_apiserver"
   addr: "127.0.0.1:3306"
   username: "root"
-  password: "123456"
+  password: "123456"                  # 支持 file:///run/secrets/db_password 或 env:DB_PASSWORD
+  replicas: []                        # 只读副本, 例如 [{addr: "127.0.0.1:3307"}], name/username/password 默认和主库相同
+  replica_check_interval: "10s"       # 只读副本健康检查的间隔
+
+sd:                                   # /sd/disk, /sd/cpu 和 /sd/ram 的告警阈值, 超过 warning 返回 429, 超过 critical 返回 503
+  disk:
+    mounts: ["/"]                     # 检查的挂载点
+    warning: 90                       # 磁盘使用率 (%)
+    critical: 95
+  cpu:
+    warning: 0.75                     # 每个核的 5 分钟平均负载
+    critical: 0.9
+  ram:
+    warning: 90                       # 内存使用率 (%)
+    critical: 95
 
-docker_db:
-  name: "db_apiserver"
-  addr: "127.0.0.1:3306"
-  username: "root"
-  password: "123456"
 `)
 
 type Config struct {
-	Name string
+	Name    string
+	Profile string
+
+	// files are the config files merged in order, data are the contents currently applied.
+	files []string
+	data  [][]byte
 }
 
 type ConfYaml struct {
-	Core SectionCore `yaml:"core"`
-	Log  SectionLog  `yaml:"log"`
-	Db   SectionDb   `yaml:"db"`
-	DockerDb SectionDockerDb  `yaml:"db"`
+	Core SectionCore `yaml:"core" mapstructure:"core"`
+	Log  SectionLog  `yaml:"log" mapstructure:"log"`
+	Db   SectionDb   `yaml:"db" mapstructure:"db"`
+	SD   SectionSD   `yaml:"sd" mapstructure:"sd"`
 }
 
 // SectionCore is sub section of config.
 type SectionCore struct {
-	Enabled			 bool						`yaml:"enabled"`
-	Mode         string         `yaml:"mode"`
-	Name         string         `yaml:"name"`
-	Address      string         `yaml:"address"`
-	Port         string         `yaml:"port"`
-	MaxPingCount int            `yaml:"max_ping_count"`
-	JwtSecret    string         `yaml:"jwt_secret"`
-	TLS          SectionTLS     `yaml:"tls"`
-	AutoTLS      SectionAutoTLS `yaml:"auto_tls"`
+	Enabled             bool             `yaml:"enabled" mapstructure:"enabled"`
+	Mode                string           `yaml:"mode" mapstructure:"mode"`
+	Name                string           `yaml:"name" mapstructure:"name"`
+	Address             string           `yaml:"address" mapstructure:"address"`
+	Port                string           `yaml:"port" mapstructure:"port"`
+	MaxPingCount        int              `yaml:"max_ping_count" mapstructure:"max_ping_count"`
+	AlwaysOK            bool             `yaml:"always_ok" mapstructure:"always_ok"`
+	Language            string           `yaml:"language" mapstructure:"language"`
+	ShutdownTimeout     time.Duration    `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout"`
+	ShutdownDelay       time.Duration    `yaml:"shutdown_delay" mapstructure:"shutdown_delay"`
+	JwtSecret           string           `yaml:"jwt_secret" mapstructure:"jwt_secret" secret:"true"`
+	JwtTimeout          time.Duration    `yaml:"jwt_timeout" mapstructure:"jwt_timeout"`
+	RefreshTokenTimeout time.Duration    `yaml:"refresh_token_timeout" mapstructure:"refresh_token_timeout"`
+	TLS                 SectionTLS       `yaml:"tls" mapstructure:"tls"`
+	AutoTLS             SectionAutoTLS   `yaml:"auto_tls" mapstructure:"auto_tls"`
+	HSTS                SectionHSTS      `yaml:"hsts" mapstructure:"hsts"`
+	CORS                SectionCORS      `yaml:"cors" mapstructure:"cors"`
+	RateLimit           SectionRateLimit `yaml:"rate_limit" mapstructure:"rate_limit"`
+	AccessLog           SectionAccessLog `yaml:"access_log" mapstructure:"access_log"`
 }
 
 // SectionTLS support tls
 type SectionTLS struct {
-	Port       string `yaml:"port"`
-	CertPath   string `yaml:"cert_path"`
-	KeyPath    string `yaml:"key_path"`
+	Port           string `yaml:"port" mapstructure:"port"`
+	CertPath       string `yaml:"cert_path" mapstructure:"cert_path"`
+	KeyPath        string `yaml:"key_path" mapstructure:"key_path"`
+	Redirect       bool   `yaml:"redirect" mapstructure:"redirect"`
+	ClientAuth     string `yaml:"client_auth" mapstructure:"client_auth"`
+	ClientCA       string `yaml:"client_ca" mapstructure:"client_ca"`
+	ClientIdentity string `yaml:"client_identity" mapstructure:"client_identity"`
 }
 
 // SectionAutoTLS support Let's Encrypt setting.
 type SectionAutoTLS struct {
-	Enabled bool   `yaml:"enabled"`
-	Folder  string `yaml:"folder"`
-	Host    string `yaml:"host"`
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled"`
+	Folder  string `yaml:"folder" mapstructure:"folder"`
+	Host    string `yaml:"host" mapstructure:"host"`
+}
+
+// SectionHSTS support Strict-Transport-Security header.
+type SectionHSTS struct {
+	Enabled           bool `yaml:"enabled" mapstructure:"enabled"`
+	MaxAge            int  `yaml:"max_age" mapstructure:"max_age"`
+	IncludeSubdomains bool `yaml:"include_subdomains" mapstructure:"include_subdomains"`
+	Preload           bool `yaml:"preload" mapstructure:"preload"`
+}
+
+// SectionCORS support cross-origin requests.
+type SectionCORS struct {
+	AllowOrigins []string `yaml:"allow_origins" mapstructure:"allow_origins"`
+}
+
+// SectionRateLimit support the global rate limit.
+type SectionRateLimit struct {
+	Enabled bool    `yaml:"enabled" mapstructure:"enabled"`
+	Rps     float64 `yaml:"rps" mapstructure:"rps"`
+	Burst   int     `yaml:"burst" mapstructure:"burst"`
+}
+
+// SectionAccessLog support the structured access log.
+type SectionAccessLog struct {
+	Enabled     bool     `yaml:"enabled" mapstructure:"enabled"`
+	SkipPaths   []string `yaml:"skip_paths" mapstructure:"skip_paths"`
+	Body        bool     `yaml:"body" mapstructure:"body"`
+	MaxBodySize int      `yaml:"max_body_size" mapstructure:"max_body_size"`
+	MaskFields  []string `yaml:"mask_fields" mapstructure:"mask_fields"`
 }
 
 // SectionLog is sub section of config.
 type SectionLog struct {
-	Writers        string `yaml:"writers"`
-	LoggerLevel    string `yaml:"logger_level"`
-	LoggerFile     string `yaml:"logger_file"`
-	LogFormatText  bool   `yaml:"log_format_text"`
-	RollingPolicy  string `yaml:"rollingPolicy"`
-	LogRotateDate  int    `yaml:"log_rotate_date"`
-	LogRotateSize  int    `yaml:"log_rotate_size"`
-	LogBackupCount int    `yaml:"log_backup_count"`
+	Writers        string `yaml:"writers" mapstructure:"writers"`
+	LoggerLevel    string `yaml:"logger_level" mapstructure:"logger_level"`
+	LoggerFile     string `yaml:"logger_file" mapstructure:"logger_file"`
+	LogFormatText  bool   `yaml:"log_format_text" mapstructure:"log_format_text"`
+	RollingPolicy  string `yaml:"rollingPolicy" mapstructure:"rollingpolicy"`
+	LogRotateDate  int    `yaml:"log_rotate_date" mapstructure:"log_rotate_date"`
+	LogRotateSize  int    `yaml:"log_rotate_size" mapstructure:"log_rotate_size"`
+	LogBackupCount int    `yaml:"log_backup_count" mapstructure:"log_backup_count"`
 }
 
 // SectionDb is sub section of config.
 type SectionDb struct {
-	Name     string `yaml:"name"`
-	Addr     string `yaml:"addr"`
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	Driver               string           `yaml:"driver" mapstructure:"driver"`
+	Path                 string           `yaml:"path" mapstructure:"path"`
+	AutoMigrate          bool             `yaml:"auto_migrate" mapstructure:"auto_migrate"`
+	MaxOpenConns         int              `yaml:"max_open_conns" mapstructure:"max_open_conns"`
+	MaxIdleConns         int              `yaml:"max_idle_conns" mapstructure:"max_idle_conns"`
+	ConnMaxLifetime      time.Duration    `yaml:"conn_max_lifetime" mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime      time.Duration    `yaml:"conn_max_idle_time" mapstructure:"conn_max_idle_time"`
+	LogMode              bool             `yaml:"log_mode" mapstructure:"log_mode"`
+	Charset              string           `yaml:"charset" mapstructure:"charset"`
+	Timezone             string           `yaml:"timezone" mapstructure:"timezone"`
+	TLS                  string           `yaml:"tls" mapstructure:"tls"`
+	TLSCA                string           `yaml:"tls_ca" mapstructure:"tls_ca"`
+	Timeout              time.Duration    `yaml:"timeout" mapstructure:"timeout"`
+	ReadTimeout          time.Duration    `yaml:"read_timeout" mapstructure:"read_timeout"`
+	WriteTimeout         time.Duration    `yaml:"write_timeout" mapstructure:"write_timeout"`
+	ConnectMaxWait       time.Duration    `yaml:"connect_max_wait" mapstructure:"connect_max_wait"`
+	Name                 string           `yaml:"name" mapstructure:"name"`
+	Addr                 string           `yaml:"addr" mapstructure:"addr"`
+	Username             string           `yaml:"username" mapstructure:"username"`
+	Password             string           `yaml:"password" mapstructure:"password" secret:"true"`
+	Replicas             []SectionReplica `yaml:"replicas" mapstructure:"replicas"`
+	ReplicaCheckInterval time.Duration    `yaml:"replica_check_interval" mapstructure:"replica_check_interval"`
 }
 
-// SectionDb is sub section of config.
-type SectionDockerDb struct {
-	Name     string `yaml:"name"`
-	Addr     string `yaml:"addr"`
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+// SectionReplica is a read replica of the db, the empty fields are the same as the primary.
+type SectionReplica struct {
+	Name     string `yaml:"name" mapstructure:"name"`
+	Addr     string `yaml:"addr" mapstructure:"addr"`
+	Username string `yaml:"username" mapstructure:"username"`
+	Password string `yaml:"password" mapstructure:"password" secret:"true"`
+}
+
+// SectionSD is the thresholds of the system checks.
+type SectionSD struct {
+	Disk SectionDiskCheck `yaml:"disk" mapstructure:"disk"`
+	CPU  SectionThreshold `yaml:"cpu" mapstructure:"cpu"`
+	RAM  SectionThreshold `yaml:"ram" mapstructure:"ram"`
 }
 
-func Init(cfg string) (ConfYaml, error) {
+// SectionDiskCheck is the thresholds of the disk usage of the mount points.
+type SectionDiskCheck struct {
+	Mounts   []string `yaml:"mounts" mapstructure:"mounts"`
+	Warning  float64  `yaml:"warning" mapstructure:"warning"`
+	Critical float64  `yaml:"critical" mapstructure:"critical"`
+}
+
+// SectionThreshold is the warning and critical level of a metric.
+type SectionThreshold struct {
+	Warning  float64 `yaml:"warning" mapstructure:"warning"`
+	Critical float64 `yaml:"critical" mapstructure:"critical"`
+}
+
+// LocalURL returns the url of the path on the listener of the server, prefers the plain http one.
+// The host is core.address, or localhost if the server listens on all the interfaces.
+func (c *ConfYaml) LocalURL(path string) string {
+	host := c.Core.Address
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+
+	if c.Core.Port != "" {
+		return "http://" + net.JoinHostPort(host, c.Core.Port) + path
+	}
+	port := c.Core.TLS.Port
+	if c.Core.AutoTLS.Enabled {
+		port = "443"
+	}
+	return "https://" + net.JoinHostPort(host, port) + path
+}
+
+// Init loads the config file cfg merged with the overlay of the profile, e.g. config.yaml + config.prod.yaml,
+// the profile defaults to the APISERVER_PROFILE environment variable.
+func Init(cfg, profile string) (ConfYaml, error) {
 	var confYaml ConfYaml
 	c := Config{
-		Name: cfg,
+		Name:    cfg,
+		Profile: profileOf(profile),
 	}
 
 	// 初始化配置文件
 	confYaml, err := c.initConfig()
 	if err != nil {
-		return confYaml, nil
+		return confYaml, err
 	}
 
 	// 初始化日志包
 	c.initLog(&confYaml)
 
+	// 日志配置变化时重新初始化日志包
+	Store(&confYaml)
+	Subscribe(func(old, conf *ConfYaml) {
+		if old.Log != conf.Log {
+			c.initLog(conf)
+		}
+	})
+
 	// 监控配置文件变化并热加载程序
 	c.watchConfig()
 
 	return confYaml, nil
 }
 
+// Load reads and validates the config, without initializing the log package and watching the files.
+func Load(cfg, profile string) (ConfYaml, error) {
+	c := Config{
+		Name:    cfg,
+		Profile: profileOf(profile),
+	}
+	return c.initConfig()
+}
+
+// Template returns the commented default config.
+func Template() []byte {
+	return defaultConf
+}
+
 // 初始化配置文件
 func (c *Config) initConfig() (ConfYaml, error) {
-	var confYaml ConfYaml
+	// 每次加载使用新的 viper, 不修改全局的实例
+	v := viper.New()
+	setupViper(v)
+	applyProfile(v, c.Profile)
+
+	// 没有指定配置文件且默认的配置文件不存在时使用默认配置
+	files, err := configFiles(c.Name, c.Profile)
+	if err != nil {
+		return ConfYaml{}, err
+	}
+	data, err := readFiles(files)
+	if err != nil {
+		return ConfYaml{}, err
+	}
+
+	// viper解析配置文件, 按顺序合并
+	if err := readConfig(v, data); err != nil {
+		return ConfYaml{}, err
+	}
+	c.files, c.data = files, data
+	loaded = v
+
+	confYaml, err := loadConfig(v)
+	if err != nil {
+		return confYaml, err
+	}
 
+	return confYaml, confYaml.Validate()
+}
+
+// setupViper reads the config as YAML over the defaultConf,
+// the environment variables prefixed by APISERVER override it.
+func setupViper(v *viper.Viper) {
 	// 设置配置文件格式为YAML
-	viper.SetConfigType("yaml")
+	v.SetConfigType("yaml")
 
 	// 读取匹配的环境变量
-	viper.AutomaticEnv()
+	v.AutomaticEnv()
 
 	// 读取环境变量的前缀为APISERVER
-	viper.SetEnvPrefix("APISERVER")
+	v.SetEnvPrefix("APISERVER")
 
 	replacer := strings.NewReplacer(".", "_")
-	viper.SetEnvKeyReplacer(replacer)
-
-	if c.Name != "" {
-		// 如果指定了配置文件，则解析指定的配置文件
-		viper.SetConfigFile(c.Name)
-	} else {
-		// 如果没有指定配置文件，则解析默认的配置文件
-		viper.AddConfigPath("src/config")
-		viper.SetConfigName("config")
-	}
-
-	// viper解析配置文件
-	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
-	} else {
-		// load default config
-		viper.ReadConfig(bytes.NewBuffer(defaultConf))
-	}
-
-	// Core
-	confYaml.Core.Enabled = viper.GetBool("core.enabled")
-	confYaml.Core.Mode = viper.GetString("core.mode")
-	confYaml.Core.Name = viper.GetString("core.name")
-	confYaml.Core.Address = viper.GetString("core.")
-	confYaml.Core.Port = viper.GetString("core.port")
-	confYaml.Core.MaxPingCount = viper.GetInt("core.max_ping_count")
-	confYaml.Core.JwtSecret = viper.GetString("core.jwt_secret")
-	confYaml.Core.TLS.Port = viper.GetString("core.tls.port")
-	confYaml.Core.TLS.CertPath = viper.GetString("core.tls.cert_path")
-	confYaml.Core.TLS.KeyPath = viper.GetString("core.tls.key_path")
-	confYaml.Core.AutoTLS.Enabled = viper.GetBool("core.auto_tls.enabled")
-	confYaml.Core.AutoTLS.Folder = viper.GetString("core.auto_tls.folder")
-	confYaml.Core.AutoTLS.Host = viper.GetString("core.auto_tls.host")
-
-	// Log
-	confYaml.Log.Writers = viper.GetString("log.writers")
-	confYaml.Log.LoggerLevel = viper.GetString("log.logger_level")
-	confYaml.Log.LoggerFile = viper.GetString("log.logger_file")
-	confYaml.Log.LogFormatText = viper.GetBool("log.log_format_text")
-	confYaml.Log.RollingPolicy = viper.GetString("log.rollingPolicy")
-	confYaml.Log.LogRotateDate = viper.GetInt("log.log_rotate_date")
-	confYaml.Log.LogRotateSize = viper.GetInt("log.log_rotate_size")
-	confYaml.Log.LogBackupCount = viper.GetInt("log.log_backup_count")
-
-	// Db
-	confYaml.Db.Name = viper.GetString("db.name")
-	confYaml.Db.Addr = viper.GetString("db.addr")
-	confYaml.Db.Username = viper.GetString("db.username")
-	confYaml.Db.Password = viper.GetString("db.password")
-
-	// DockerDb
-	confYaml.DockerDb.Name = viper.GetString("docker_db.name")
-	confYaml.DockerDb.Addr = viper.GetString("docker_db.addr")
-	confYaml.DockerDb.Username = viper.GetString("docker_db.username")
-	confYaml.DockerDb.Password = viper.GetString("docker_db.password")
+	v.SetEnvKeyReplacer(replacer)
+
+	// 默认配置, 配置文件中没有的配置项使用默认值
+	defaults := viper.New()
+	defaults.SetConfigType("yaml")
+	if err := defaults.ReadConfig(bytes.NewReader(defaultConf)); err != nil {
+		panic(err)
+	}
+	for _, key := range defaults.AllKeys() {
+		v.SetDefault(key, defaults.Get(key))
+	}
+}
+
+// loadConfig unmarshals the settings of the viper instance, and resolves the secret references.
+func loadConfig(v *viper.Viper) (ConfYaml, error) {
+	var confYaml ConfYaml
+
+	if err := v.Unmarshal(&confYaml); err != nil {
+		return confYaml, fmt.Errorf("parse config: %v", err)
+	}
+
+	// 解析 file:// 和 env: 引用的密钥
+	if err := resolveSecrets(&confYaml); err != nil {
+		return confYaml, err
+	}
 
 	return confYaml, nil
 }
@@ -226,9 +414,50 @@ func (c *Config) initLog(conf *ConfYaml) {
 }
 
 // 监控配置文件变化并热加载程序
+// The directories are watched, as the files are often replaced by renaming or symlink swaps.
 func (c *Config) watchConfig() {
-	viper.WatchConfig()
-	viper.OnConfigChange(func(e fsnotify.Event) {
-		log.Infof("Config file changed: %s", e.Name)
-	})
+	if len(c.files) == 0 {
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Errorf(err, "Watch the config files failed.")
+		return
+	}
+	dirs := map[string]bool{}
+	for _, file := range c.files {
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			log.Errorf(err, "Watch the config directory %s failed.", dir)
+		}
+	}
+
+	go func() {
+		// Wait for the editors and the config management tools to finish writing.
+		var reload <-chan time.Time
+
+		for {
+			select {
+			case e, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				log.Debugf("Config directory changed: %s", e.Name)
+				reload = time.After(100 * time.Millisecond)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf(err, "Watch the config files failed.")
+			case <-reload:
+				reload = nil
+				if err := c.reload(); err != nil {
+					log.Errorf(err, "Reload config files %s failed, keep the previous config.", strings.Join(c.files, ", "))
+				}
+			}
+		}
+	}()
 }
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// defaultConfigFile is read if the config file isn't specified.
+const defaultConfigFile = "src/config/config.yaml"
+
+// profileOf returns the profile, defaults to the APISERVER_PROFILE environment variable.
+func profileOf(profile string) string {
+	if profile == "" {
+		profile = os.Getenv("APISERVER_PROFILE")
+	}
+	return profile
+}
+
+// configFiles returns the base config file and the overlay of the profile, e.g. config.yaml and config.prod.yaml.
+// The default base file is optional, the specified one and the overlay must exist.
+func configFiles(name, profile string) ([]string, error) {
+	var files []string
+
+	base := name
+	if base == "" {
+		base = defaultConfigFile
+	}
+	if _, err := os.Stat(base); err == nil {
+		files = append(files, base)
+	} else if name != "" {
+		return nil, fmt.Errorf("read config file: %v", err)
+	}
+
+	if profile != "" {
+		ext := filepath.Ext(base)
+		overlay := strings.TrimSuffix(base, ext) + "." + profile + ext
+		if _, err := os.Stat(overlay); err != nil {
+			return nil, fmt.Errorf("read config file of profile %s: %v", profile, err)
+		}
+		files = append(files, overlay)
+	}
+
+	return files, nil
+}
+
+func readFiles(files []string) ([][]byte, error) {
+	var data [][]byte
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %v", err)
+		}
+		data = append(data, b)
+	}
+	return data, nil
+}
+
+// readConfig deep merges the config files in order, the later ones override the keys of the former.
+func readConfig(v *viper.Viper, data [][]byte) error {
+	v.ReadConfig(bytes.NewReader(nil))
+	for _, b := range data {
+		if err := v.MergeConfig(bytes.NewReader(b)); err != nil {
+			return fmt.Errorf("parse config file: %v", err)
+		}
+	}
+	return nil
+}
+
+// applyProfile defaults `core.mode` from the profile, the config files and the environment variables override it.
+func applyProfile(v *viper.Viper, profile string) {
+	switch profile {
+	case "":
+	case "prod", "production", "staging":
+		v.SetDefault("core.mode", "release")
+	case "test":
+		v.SetDefault("core.mode", "test")
+	default:
+		v.SetDefault("core.mode", "debug")
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lexkong/log"
+	"github.com/spf13/viper"
+)
+
+var (
+	// current holds the *ConfYaml applied at last.
+	current atomic.Value
+
+	mu          sync.Mutex
+	subscribers []func(old, conf *ConfYaml)
+	pending     []string
+)
+
+// Current returns the config applied at last, it's updated when the config file changes.
+// The `src.Conf` is the snapshot of the startup.
+func Current() *ConfYaml {
+	conf, _ := current.Load().(*ConfYaml)
+	return conf
+}
+
+// Store swaps the current config, the tests use it to change the settings.
+func Store(conf *ConfYaml) {
+	current.Store(conf)
+}
+
+// Subscribe registers a function called with the previous and the new config after a reload.
+func Subscribe(fn func(old, conf *ConfYaml)) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	subscribers = append(subscribers, fn)
+}
+
+// Pending returns the changed settings which take effect after a restart.
+func Pending() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return append([]string(nil), pending...)
+}
+
+// reload parses and validates the config files, then swaps the config and notifies the subscribers.
+// The previous config is kept if the files are invalid.
+func (c *Config) reload() error {
+	data, err := readFiles(c.files)
+	if err != nil {
+		return err
+	}
+	if unchanged(c.data, data) {
+		return nil
+	}
+	log.Infof("Config files changed: %s", strings.Join(c.files, ", "))
+
+	v := viper.New()
+	setupViper(v)
+	applyProfile(v, c.Profile)
+	if err := readConfig(v, data); err != nil {
+		return err
+	}
+
+	conf, err := loadConfig(v)
+	if err == nil {
+		err = conf.Validate()
+	}
+	if err != nil {
+		return err
+	}
+
+	c.data = data
+
+	old := Current()
+	Store(&conf)
+
+	mu.Lock()
+	for _, key := range restartRequired(old, &conf) {
+		log.Warnf("Config %s changed, restart the server to apply it.", key)
+		if !contains(pending, key) {
+			pending = append(pending, key)
+		}
+	}
+	fns := append([]func(old, conf *ConfYaml){}, subscribers...)
+	mu.Unlock()
+
+	for _, fn := range fns {
+		fn(old, &conf)
+	}
+
+	log.Info("Config files reloaded.")
+	return nil
+}
+
+func unchanged(old, data [][]byte) bool {
+	if len(old) != len(data) {
+		return false
+	}
+	for i := range data {
+		if !bytes.Equal(old[i], data[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// restartRequired returns the changed settings which are applied only at startup.
+func restartRequired(old, conf *ConfYaml) []string {
+	if old == nil {
+		return nil
+	}
+
+	var keys []string
+	check := func(key string, a, b interface{}) {
+		if fmt.Sprint(a) != fmt.Sprint(b) {
+			keys = append(keys, key)
+		}
+	}
+
+	check("core.enabled", old.Core.Enabled, conf.Core.Enabled)
+	check("core.mode", old.Core.Mode, conf.Core.Mode)
+	check("core.address", old.Core.Address, conf.Core.Address)
+	check("core.port", old.Core.Port, conf.Core.Port)
+	check("core.shutdown_timeout", old.Core.ShutdownTimeout, conf.Core.ShutdownTimeout)
+	check("core.shutdown_delay", old.Core.ShutdownDelay, conf.Core.ShutdownDelay)
+	check("core.tls.port", old.Core.TLS.Port, conf.Core.TLS.Port)
+	check("core.tls.redirect", old.Core.TLS.Redirect, conf.Core.TLS.Redirect)
+	// Enabling or disabling https needs a new listener.
+	check("core.tls.enabled", old.Core.TLS.CertPath != "", conf.Core.TLS.CertPath != "")
+	check("core.auto_tls", old.Core.AutoTLS, conf.Core.AutoTLS)
+	check("core.hsts", old.Core.HSTS, conf.Core.HSTS)
+	check("db.driver", old.Db.Driver, conf.Db.Driver)
+	check("db.path", old.Db.Path, conf.Db.Path)
+	check("db.name", old.Db.Name, conf.Db.Name)
+	check("db.addr", old.Db.Addr, conf.Db.Addr)
+	check("db.username", old.Db.Username, conf.Db.Username)
+	check("db.password", old.Db.Password, conf.Db.Password)
+	check("db.replicas", old.Db.Replicas, conf.Db.Replicas)
+	check("db.log_mode", old.Db.LogMode, conf.Db.LogMode)
+	check("db.charset", old.Db.Charset, conf.Db.Charset)
+	check("db.timezone", old.Db.Timezone, conf.Db.Timezone)
+	check("db.tls", old.Db.TLS, conf.Db.TLS)
+	check("db.tls_ca", old.Db.TLSCA, conf.Db.TLSCA)
+	check("db.timeout", old.Db.Timeout, conf.Db.Timeout)
+	check("db.read_timeout", old.Db.ReadTimeout, conf.Db.ReadTimeout)
+	check("db.write_timeout", old.Db.WriteTimeout, conf.Db.WriteTimeout)
+	check("db.replica_check_interval", old.Db.ReplicaCheckInterval, conf.Db.ReplicaCheckInterval)
+
+	return keys
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
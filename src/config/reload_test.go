@@ -0,0 +1,78 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	c := &Config{Name: file, files: []string{file}, data: [][]byte{defaultConf}}
+
+	initial := loadConfigBytes(t, defaultConf)
+	Store(&initial)
+
+	var notified *ConfYaml
+	Subscribe(func(old, conf *ConfYaml) {
+		notified = conf
+	})
+
+	// Invalid edits are rejected, the previous config is kept.
+	invalid := strings.Replace(string(defaultConf), `logger_level: "DEBUG"`, `logger_level: "VERBOSE"`, 1)
+	assert.NoError(t, ioutil.WriteFile(file, []byte(invalid), 0644))
+	assert.Error(t, c.reload())
+	assert.Equal(t, "DEBUG", Current().Log.LoggerLevel)
+	assert.Nil(t, notified)
+
+	// Valid edits are applied, the port change waits for a restart.
+	valid := strings.Replace(string(defaultConf), `logger_level: "DEBUG"`, `logger_level: "INFO"`, 1)
+	valid = strings.Replace(valid, `port: "9090"`, `port: "9091"`, 1)
+	assert.NoError(t, ioutil.WriteFile(file, []byte(valid), 0644))
+	assert.NoError(t, c.reload())
+	assert.Equal(t, "INFO", Current().Log.LoggerLevel)
+	assert.Equal(t, "INFO", notified.Log.LoggerLevel)
+	// The handlers read the swapped config, the global viper is left alone.
+	assert.Empty(t, viper.GetString("log.logger_level"))
+	assert.Contains(t, Pending(), "core.port")
+	assert.NotContains(t, Pending(), "log.logger_level")
+}
+
+func TestReloadSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("APISERVER_TEST_JWT_SECRET", "jwt-s3cret")
+	defer os.Unsetenv("APISERVER_TEST_JWT_SECRET")
+
+	file := filepath.Join(dir, "config.yaml")
+	c := &Config{Name: file, files: []string{file}, data: [][]byte{defaultConf}}
+	initial := loadConfigBytes(t, defaultConf)
+	Store(&initial)
+
+	// The reloaded secrets are resolved in the swapped config.
+	data := strings.Replace(string(defaultConf), `jwt_secret: "`+defaultJwtSecret+`"`, `jwt_secret: "env:APISERVER_TEST_JWT_SECRET"`, 1)
+	assert.NoError(t, ioutil.WriteFile(file, []byte(data), 0644))
+	assert.NoError(t, c.reload())
+	assert.Equal(t, "jwt-s3cret", Current().Core.JwtSecret)
+}
+
+func loadConfigBytes(t *testing.T, data []byte) ConfYaml {
+	v := viper.New()
+	setupViper(v)
+	assert.NoError(t, v.ReadConfig(bytes.NewReader(data)))
+	conf, err := loadConfig(v)
+	assert.NoError(t, err)
+	return conf
+}
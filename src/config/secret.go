@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The references of the secret settings, e.g.
+//
+//	password: "file:///run/secrets/db_password"
+//	jwt_secret: "env:JWT_SECRET"
+const (
+	fileRef = "file://"
+	envRef  = "env:"
+)
+
+// resolveSecrets replaces the references of the fields tagged `secret:"true"` with the values,
+// the settings are read from the ConfYaml only, the references stay in viper.
+func resolveSecrets(conf *ConfYaml) error {
+	var errs ValidationErrors
+
+	walk("", reflect.ValueOf(conf).Elem(), func(key string, field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") != "true" || value.Kind() != reflect.String {
+			return
+		}
+
+		secret, err := resolveSecret(value.String())
+		if err != nil {
+			// The error never contains the value.
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+			return
+		}
+		value.SetString(secret)
+	})
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// resolveSecret returns the content of the file:// reference or the variable of the env: reference,
+// the other values are returned as is.
+func resolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, fileRef):
+		path := strings.TrimPrefix(value, fileRef)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read the secret file %s failed", path)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, envRef):
+		name := strings.TrimPrefix(value, envRef)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	}
+	return value, nil
+}
+
+// walk visits the struct fields, the keys are the yaml tags joined by dots,
+// the items of the struct lists are keyed by the indexes, e.g. db.replicas.0.addr.
+func walk(prefix string, v reflect.Value, fn func(key string, field reflect.StructField, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct && value.Type().PkgPath() == t.PkgPath() {
+			walk(key, value, fn)
+			continue
+		}
+		if value.Kind() == reflect.Slice && value.Type().Elem().PkgPath() == t.PkgPath() {
+			for j := 0; j < value.Len(); j++ {
+				walk(key+"."+strconv.Itoa(j), value.Index(j), fn)
+			}
+			continue
+		}
+		fn(key, field, value)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// The sources of the settings.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// redacted is shown instead of the secrets.
+const redacted = "******"
+
+// loaded is the viper of the last Load or Init, Settings looks up the sources in it.
+var loaded *viper.Viper
+
+// Setting is a flattened setting of the effective config.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Settings flattens the config in the order of the fields, the secrets are redacted.
+// The sources are looked up in the config loaded by Load or Init,
+// flags are the keys overridden by the command line flags.
+func Settings(conf *ConfYaml, flags ...string) []Setting {
+	var settings []Setting
+	walk("", reflect.ValueOf(*conf), func(key string, field reflect.StructField, value reflect.Value) {
+		s := Setting{
+			Key:    key,
+			Value:  fmt.Sprintf("%v", value.Interface()),
+			Source: source(key, flags),
+		}
+		if field.Tag.Get("secret") == "true" && s.Value != "" {
+			s.Value = redacted
+		}
+		settings = append(settings, s)
+	})
+	return settings
+}
+
+func source(key string, flags []string) string {
+	for _, flag := range flags {
+		if flag == key {
+			return SourceFlag
+		}
+	}
+
+	env := "APISERVER_" + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+	if os.Getenv(env) != "" {
+		return SourceEnv
+	}
+
+	if loaded != nil && loaded.InConfig(key) {
+		return SourceFile
+	}
+	return SourceDefault
+}
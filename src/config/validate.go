@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate checks the settings, and reports every invalid one.
+func (c *ConfYaml) Validate() error {
+	var errs ValidationErrors
+
+	switch c.Core.Mode {
+	case "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Sprintf("core.mode: unknown mode %q, expect debug, release or test", c.Core.Mode))
+	}
+
+	if c.Core.Port != "" && !validPort(c.Core.Port) {
+		errs = append(errs, fmt.Sprintf("core.port: invalid port %q", c.Core.Port))
+	}
+	if c.Core.TLS.Port != "" && !validPort(c.Core.TLS.Port) {
+		errs = append(errs, fmt.Sprintf("core.tls.port: invalid port %q", c.Core.TLS.Port))
+	}
+
+	if (c.Core.TLS.CertPath == "") != (c.Core.TLS.KeyPath == "") {
+		errs = append(errs, "core.tls: cert_path and key_path must be set together")
+	}
+
+	// Let's Encrypt 的 http-01 验证只访问 80 端口
+	if c.Core.AutoTLS.Enabled && c.Core.Port != "80" {
+		errs = append(errs, fmt.Sprintf("core.port: must be 80 with auto_tls for the ACME challenges, got %q", c.Core.Port))
+	}
+	if c.Core.Port == "" && !c.Core.AutoTLS.Enabled && (c.Core.TLS.CertPath == "" || c.Core.TLS.KeyPath == "") {
+		errs = append(errs, "core.port: must be set when https is disabled")
+	}
+
+	switch c.Core.TLS.ClientAuth {
+	case "", "none", "request", "require":
+	default:
+		errs = append(errs, fmt.Sprintf("core.tls.client_auth: unknown mode %q, expect none, request or require", c.Core.TLS.ClientAuth))
+	}
+	// 明文端口不校验客户端证书, 只能重定向到 HTTPS
+	if c.Core.TLS.ClientAuth == "require" && c.Core.TLS.CertPath != "" && c.Core.Port != "" && !c.Core.TLS.Redirect {
+		errs = append(errs, "core.tls.client_auth: require needs redirect, or an empty core.port, the plain http port skips the client certificates")
+	}
+
+	if c.Core.Mode == "release" && c.Core.JwtSecret == defaultJwtSecret {
+		errs = append(errs, "core.jwt_secret: the default secret can't be used in release mode")
+	}
+	if c.Core.JwtSecret == "" {
+		errs = append(errs, "core.jwt_secret: must be set")
+	}
+
+	if c.Core.RateLimit.Enabled && (c.Core.RateLimit.Rps <= 0 || c.Core.RateLimit.Burst <= 0) {
+		errs = append(errs, "core.rate_limit: rps and burst must be positive")
+	}
+
+	switch c.Core.Language {
+	case "en", "zh-CN":
+	default:
+		errs = append(errs, fmt.Sprintf("core.language: unsupported language %q, expect en or zh-CN", c.Core.Language))
+	}
+
+	if c.Core.AccessLog.MaxBodySize < 0 {
+		errs = append(errs, "core.access_log.max_body_size: can't be negative")
+	}
+
+	switch strings.ToUpper(c.Log.LoggerLevel) {
+	case "DEBUG", "INFO", "WARN", "ERROR", "FATAL":
+	default:
+		errs = append(errs, fmt.Sprintf("log.logger_level: unknown level %q, expect DEBUG, INFO, WARN, ERROR or FATAL", c.Log.LoggerLevel))
+	}
+
+	switch c.Db.Driver {
+	case "mysql", "sqlite3":
+	default:
+		errs = append(errs, fmt.Sprintf("db.driver: unknown driver %q, expect mysql or sqlite3", c.Db.Driver))
+	}
+
+	if c.Db.MaxOpenConns < 0 || c.Db.MaxIdleConns < 0 {
+		errs = append(errs, "db: max_open_conns and max_idle_conns can't be negative")
+	}
+
+	if c.Db.Charset == "" {
+		errs = append(errs, "db.charset: must be set")
+	}
+
+	if _, err := time.LoadLocation(c.Db.Timezone); err != nil {
+		errs = append(errs, fmt.Sprintf("db.timezone: unknown timezone %q", c.Db.Timezone))
+	}
+
+	for i, r := range c.Db.Replicas {
+		if r.Addr == "" {
+			errs = append(errs, fmt.Sprintf("db.replicas[%d].addr: must be set", i))
+		}
+	}
+	if len(c.Db.Replicas) > 0 && c.Db.ReplicaCheckInterval <= 0 {
+		errs = append(errs, "db.replica_check_interval: must be positive with replicas")
+	}
+
+	switch c.Db.TLS {
+	case "", "false", "true", "skip-verify", "preferred":
+	default:
+		errs = append(errs, fmt.Sprintf("db.tls: unknown mode %q, expect false, true, skip-verify or preferred", c.Db.TLS))
+	}
+	// tls_ca 总是校验服务端证书
+	if c.Db.TLSCA != "" && c.Db.TLS != "" && c.Db.TLS != "true" {
+		errs = append(errs, fmt.Sprintf("db.tls: can't be %q with tls_ca, leave it empty or set true", c.Db.TLS))
+	}
+
+	if len(c.SD.Disk.Mounts) == 0 {
+		errs = append(errs, "sd.disk.mounts: must be set")
+	}
+	errs = append(errs, validThreshold("sd.disk", c.SD.Disk.Warning, c.SD.Disk.Critical, 100)...)
+	errs = append(errs, validThreshold("sd.cpu", c.SD.CPU.Warning, c.SD.CPU.Critical, 0)...)
+	errs = append(errs, validThreshold("sd.ram", c.SD.RAM.Warning, c.SD.RAM.Critical, 100)...)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidationErrors is the list of the invalid settings.
+type ValidationErrors []string
+
+func (e ValidationErrors) Error() string {
+	return "invalid config:\n  " + strings.Join(e, "\n  ")
+}
+
+// validThreshold checks 0 < warning <= critical, and critical <= max if max is set.
+func validThreshold(key string, warning, critical, max float64) []string {
+	switch {
+	case warning <= 0 || critical <= 0:
+		return []string{key + ": warning and critical must be positive"}
+	case warning > critical:
+		return []string{key + ": warning can't be greater than critical"}
+	case max > 0 && critical > max:
+		return []string{fmt.Sprintf("%s: critical can't be greater than %g", key, max)}
+	}
+	return nil
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
@@ -0,0 +1,24 @@
+package migration
+
+func init() {
+	Register(Migration{
+		Version: 20200317000001,
+		Name:    "create_tb_users",
+		Up: []string{
+			"CREATE TABLE `tb_users` (" +
+				"`id` bigint(20) unsigned NOT NULL AUTO_INCREMENT," +
+				"`username` varchar(255) NOT NULL," +
+				"`password` varchar(255) NOT NULL," +
+				"`createdAt` timestamp NULL DEFAULT NULL," +
+				"`updatedAt` timestamp NULL DEFAULT NULL," +
+				"`deletedAt` timestamp NULL DEFAULT NULL," +
+				"PRIMARY KEY (`id`)," +
+				"UNIQUE KEY `username` (`username`)," +
+				"KEY `idx_tb_users_deletedAt` (`deletedAt`)" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		Down: []string{
+			"DROP TABLE `tb_users`",
+		},
+	})
+}
@@ -0,0 +1,29 @@
+package migration
+
+func init() {
+	Register(Migration{
+		Version: 20200317000002,
+		Name:    "create_tb_refresh_tokens",
+		Up: []string{
+			"CREATE TABLE `tb_refresh_tokens` (" +
+				"`id` bigint(20) unsigned NOT NULL AUTO_INCREMENT," +
+				"`userId` bigint(20) unsigned NOT NULL," +
+				"`family` varchar(64) NOT NULL," +
+				"`tokenHash` char(64) NOT NULL," +
+				"`expiresAt` timestamp NULL DEFAULT NULL," +
+				"`revokedAt` timestamp NULL DEFAULT NULL," +
+				"`createdAt` timestamp NULL DEFAULT NULL," +
+				"`updatedAt` timestamp NULL DEFAULT NULL," +
+				"`deletedAt` timestamp NULL DEFAULT NULL," +
+				"PRIMARY KEY (`id`)," +
+				"UNIQUE KEY `tokenHash` (`tokenHash`)," +
+				"KEY `idx_tb_refresh_tokens_userId` (`userId`)," +
+				"KEY `idx_tb_refresh_tokens_family` (`family`)," +
+				"KEY `idx_tb_refresh_tokens_deletedAt` (`deletedAt`)" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		Down: []string{
+			"DROP TABLE `tb_refresh_tokens`",
+		},
+	})
+}
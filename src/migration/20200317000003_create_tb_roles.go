@@ -0,0 +1,45 @@
+package migration
+
+func init() {
+	Register(Migration{
+		Version: 20200317000003,
+		Name:    "create_tb_roles",
+		Up: []string{
+			"CREATE TABLE `tb_roles` (" +
+				"`id` bigint(20) unsigned NOT NULL AUTO_INCREMENT," +
+				"`name` varchar(32) NOT NULL," +
+				"`description` varchar(255) NOT NULL DEFAULT ''," +
+				"`createdAt` timestamp NULL DEFAULT NULL," +
+				"`updatedAt` timestamp NULL DEFAULT NULL," +
+				"`deletedAt` timestamp NULL DEFAULT NULL," +
+				"PRIMARY KEY (`id`)," +
+				"UNIQUE KEY `name` (`name`)," +
+				"KEY `idx_tb_roles_deletedAt` (`deletedAt`)" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			"CREATE TABLE `tb_role_permissions` (" +
+				"`id` bigint(20) unsigned NOT NULL AUTO_INCREMENT," +
+				"`roleId` bigint(20) unsigned NOT NULL," +
+				"`permission` varchar(64) NOT NULL," +
+				"PRIMARY KEY (`id`)," +
+				"UNIQUE KEY `roleId_permission` (`roleId`, `permission`)" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			"CREATE TABLE `tb_user_roles` (" +
+				"`id` bigint(20) unsigned NOT NULL AUTO_INCREMENT," +
+				"`userId` bigint(20) unsigned NOT NULL," +
+				"`roleId` bigint(20) unsigned NOT NULL," +
+				"`createdAt` timestamp NULL DEFAULT NULL," +
+				"PRIMARY KEY (`id`)," +
+				"UNIQUE KEY `userId_roleId` (`userId`, `roleId`)" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			// 默认角色, 管理员用 `apiserver user create <username> --role admin` 创建
+			"INSERT INTO `tb_roles` (`id`, `name`, `description`, `createdAt`, `updatedAt`) VALUES " +
+				"(1, 'admin', 'Administrator', NOW(), NOW()), (2, 'user', 'Regular user', NOW(), NOW())",
+			"INSERT INTO `tb_role_permissions` (`roleId`, `permission`) VALUES (1, '*'), (2, 'users:get')",
+		},
+		Down: []string{
+			"DROP TABLE `tb_user_roles`",
+			"DROP TABLE `tb_role_permissions`",
+			"DROP TABLE `tb_roles`",
+		},
+	})
+}
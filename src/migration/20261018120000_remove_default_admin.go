@@ -0,0 +1,17 @@
+package migration
+
+func init() {
+	Register(Migration{
+		Version: 20261018120000,
+		Name:    "remove_default_admin",
+		Up: []string{
+			// 删除旧版本迁移创建的默认管理员 admin, 只删除仍使用公开密码 admin 的
+			"DELETE FROM `tb_user_roles` WHERE `userId` IN (SELECT `id` FROM (SELECT `id` FROM `tb_users` " +
+				"WHERE `username` = 'admin' AND `password` = '$2a$10$veGcArz47VGj7l9xN7g2iuT9TF21jLI1YGXarGzvARNdnt4inC9PG') AS `t`)",
+			"DELETE FROM `tb_users` " +
+				"WHERE `username` = 'admin' AND `password` = '$2a$10$veGcArz47VGj7l9xN7g2iuT9TF21jLI1YGXarGzvARNdnt4inC9PG'",
+		},
+		// The admin with the public password isn't restored.
+		Down: []string{},
+	})
+}
@@ -0,0 +1,410 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lexkong/log"
+)
+
+const (
+	// 迁移记录表
+	tableName = "schema_migrations"
+	// MySQL advisory lock, 防止多个实例同时执行迁移
+	lockName = "apiserver.schema_migrations"
+)
+
+// lockTimeout is the seconds to wait for the lock held by another instance.
+var lockTimeout = 60
+
+// Migration is a versioned schema change, Up and Down are the SQL statements executed in order.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// Status is the state of a migration in the database.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+	// Dirty 迁移执行失败, 数据库可能只执行了部分语句
+	Dirty bool
+}
+
+// record is a row of the migration table.
+type record struct {
+	appliedAt time.Time
+	dirty     bool
+}
+
+var migrations = make(map[uint64]Migration)
+
+// Register adds the migration, it's called from the init function of each migration file.
+func Register(m Migration) {
+	if _, ok := migrations[m.Version]; ok {
+		panic(fmt.Sprintf("migration: duplicate version %d", m.Version))
+	}
+	migrations[m.Version] = m
+}
+
+// All returns the registered migrations ordered by version.
+func All() []Migration {
+	all := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Version < all[j].Version
+	})
+	return all
+}
+
+// Migrator applies the migrations to the database.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New returns the migrator of the registered migrations, they are written for MySQL.
+// The migration table also works with SQLite, which is used by the tests.
+func New(db *gorm.DB) *Migrator {
+	return &Migrator{db: db, migrations: All()}
+}
+
+func (m *Migrator) check() error {
+	if m.db == nil || m.db.DB() == nil {
+		return errors.New("migration: database is not connected")
+	}
+	switch name := m.db.Dialect().GetName(); name {
+	case "mysql", "sqlite3":
+	default:
+		return fmt.Errorf("migration: dialect %s is not supported", name)
+	}
+	return nil
+}
+
+func (m *Migrator) mysql() bool {
+	return m.db.Dialect().GetName() == "mysql"
+}
+
+// sqliteLock serializes the migrations of SQLite, it has no advisory lock.
+var sqliteLock sync.Mutex
+
+// withLock runs fn holding the advisory lock, the lock belongs to a dedicated connection.
+func (m *Migrator) withLock(fn func() error) error {
+	if err := m.check(); err != nil {
+		return err
+	}
+
+	if !m.mysql() {
+		sqliteLock.Lock()
+		defer sqliteLock.Unlock()
+		return m.createTable(fn)
+	}
+
+	ctx := context.Background()
+	conn, err := m.db.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, lockTimeout).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("migration: timeout waiting for the lock %s", lockName)
+	}
+	defer func() {
+		var released sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", lockName).Scan(&released); err != nil {
+			log.Errorf(err, "Release the migration lock failed.")
+		}
+	}()
+
+	return m.createTable(fn)
+}
+
+// createTable creates the migration table if it doesn't exist, then runs fn.
+func (m *Migrator) createTable(fn func() error) error {
+	version, options := "bigint", ""
+	if m.mysql() {
+		version, options = "bigint(20) unsigned", " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	}
+	if err := m.db.Exec("CREATE TABLE IF NOT EXISTS `" + tableName + "` (" +
+		"`version` " + version + " NOT NULL," +
+		"`name` varchar(255) NOT NULL," +
+		"`appliedAt` timestamp NULL DEFAULT NULL," +
+		"`dirty` tinyint(1) NOT NULL DEFAULT 0," +
+		"PRIMARY KEY (`version`)" +
+		")" + options).Error; err != nil {
+		return err
+	}
+	// 旧版本的迁移记录表没有 dirty 字段
+	if !m.hasDirty() {
+		if err := m.db.Exec("ALTER TABLE `" + tableName + "` ADD COLUMN `dirty` tinyint(1) NOT NULL DEFAULT 0").Error; err != nil {
+			return err
+		}
+	}
+
+	return fn()
+}
+
+// hasDirty reports whether the migration table has the dirty column.
+func (m *Migrator) hasDirty() bool {
+	if m.mysql() {
+		return m.db.Dialect().HasColumn(tableName, "dirty")
+	}
+
+	// The dialect of SQLite only finds the double quoted columns.
+	var count int
+	m.db.DB().QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = 'dirty'", tableName).Scan(&count)
+	return count > 0
+}
+
+// applied returns the recorded versions, the time they were applied and whether they are dirty.
+func (m *Migrator) applied(ctx context.Context) (map[uint64]record, error) {
+	rows, err := m.db.DB().QueryContext(ctx, "SELECT `version`, `appliedAt`, `dirty` FROM `"+tableName+"`")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[uint64]record)
+	for rows.Next() {
+		var (
+			version   uint64
+			appliedAt *time.Time
+			r         record
+		)
+		if err := rows.Scan(&version, &appliedAt, &r.dirty); err != nil {
+			return nil, err
+		}
+		if appliedAt != nil {
+			r.appliedAt = *appliedAt
+		}
+		versions[version] = r
+	}
+	return versions, rows.Err()
+}
+
+// clean returns an error if a migration is dirty, it must be fixed by hand then forced.
+func clean(versions map[uint64]record) error {
+	for version, r := range versions {
+		if r.dirty {
+			return fmt.Errorf("migration: version %d is dirty, fix the database then run `migrate force %d`", version, version)
+		}
+	}
+	return nil
+}
+
+// Up applies all the pending migrations in order.
+func (m *Migrator) Up() (done []Migration, err error) {
+	err = m.withLock(func() error {
+		versions, err := m.applied(context.Background())
+		if err != nil {
+			return err
+		}
+		if err := clean(versions); err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			if _, ok := versions[mg.Version]; ok {
+				continue
+			}
+
+			log.Infof("Applying migration %d_%s", mg.Version, mg.Name)
+			// MySQL 的 DDL 会隐式提交事务, 先记录 dirty, 失败时保留
+			if err := m.db.Exec("INSERT INTO `"+tableName+"` (`version`, `name`, `dirty`) VALUES (?, ?, 1)",
+				mg.Version, mg.Name).Error; err != nil {
+				return err
+			}
+			if err := m.exec(mg.Up, "UPDATE `"+tableName+"` SET `dirty` = 0, `appliedAt` = ? WHERE `version` = ?",
+				time.Now(), mg.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: %v", mg.Version, mg.Name, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return
+}
+
+// Down rolls back the latest n applied migrations.
+func (m *Migrator) Down(n int) (done []Migration, err error) {
+	err = m.withLock(func() error {
+		versions, err := m.applied(context.Background())
+		if err != nil {
+			return err
+		}
+		if err := clean(versions); err != nil {
+			return err
+		}
+
+		all := m.migrations
+		for i := len(all) - 1; i >= 0 && len(done) < n; i-- {
+			mg := all[i]
+			if _, ok := versions[mg.Version]; !ok {
+				continue
+			}
+
+			log.Infof("Rolling back migration %d_%s", mg.Version, mg.Name)
+			if err := m.db.Exec("UPDATE `"+tableName+"` SET `dirty` = 1 WHERE `version` = ?", mg.Version).Error; err != nil {
+				return err
+			}
+			if err := m.exec(mg.Down, "DELETE FROM `"+tableName+"` WHERE `version` = ?", mg.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: %v", mg.Version, mg.Name, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return
+}
+
+// Force clears the dirty flag of the version after the database was fixed by hand,
+// the version is recorded as applied, or removed if applied is false.
+func (m *Migrator) Force(version uint64, applied bool) error {
+	return m.withLock(func() error {
+		versions, err := m.applied(context.Background())
+		if err != nil {
+			return err
+		}
+		if r, ok := versions[version]; !ok || !r.dirty {
+			return fmt.Errorf("migration: version %d is not dirty", version)
+		}
+
+		if !applied {
+			return m.db.Exec("DELETE FROM `"+tableName+"` WHERE `version` = ?", version).Error
+		}
+		return m.db.Exec("UPDATE `"+tableName+"` SET `dirty` = 0, `appliedAt` = ? WHERE `version` = ?",
+			time.Now(), version).Error
+	})
+}
+
+// Status returns the state of every registered migration, the queries are canceled with the ctx.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	if !m.mysql() {
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	}
+	var tables int
+	if err := m.db.DB().QueryRowContext(ctx, query, tableName).Scan(&tables); err != nil {
+		return nil, err
+	}
+
+	versions := make(map[uint64]record)
+	if tables > 0 {
+		var err error
+		if versions, err = m.applied(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	all := m.migrations
+	status := make([]Status, 0, len(all))
+	for _, mg := range all {
+		s := Status{Migration: mg}
+		if r, ok := versions[mg.Version]; ok {
+			s.Dirty = r.dirty
+			if !r.dirty {
+				t := r.appliedAt
+				s.Applied = true
+				s.AppliedAt = &t
+			}
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// Pending returns the migrations not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Migration, 0)
+	for _, s := range status {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// exec runs the statements and the update of the migration table in a transaction.
+func (m *Migrator) exec(statements []string, record string, values ...interface{}) error {
+	tx := m.db.Begin()
+	if err := tx.Error; err != nil {
+		return err
+	}
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Exec(record, values...).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+const template = `package migration
+
+func init() {
+	Register(Migration{
+		Version: %d,
+		Name:    %q,
+		Up: []string{
+			// "CREATE TABLE ...",
+		},
+		Down: []string{
+			// "DROP TABLE ...",
+		},
+	})
+}
+`
+
+// Create writes a new empty migration file into the directory, and returns the file path.
+func Create(dir, name string) (string, error) {
+	name = strings.ToLower(name)
+	if !nameRegexp.MatchString(name) {
+		return "", fmt.Errorf("migration: invalid name %q, only [a-z0-9_] is allowed", name)
+	}
+
+	version := uint64(0)
+	fmt.Sscanf(time.Now().UTC().Format("20060102150405"), "%d", &version)
+
+	file := filepath.Join(dir, fmt.Sprintf("%d_%s.go", version, name))
+	if _, err := os.Stat(file); err == nil {
+		return "", fmt.Errorf("migration: file %s already exists", file)
+	}
+
+	return file, ioutil.WriteFile(file, []byte(fmt.Sprintf(template, version, name)), 0644)
+}
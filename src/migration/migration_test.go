@@ -0,0 +1,178 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_a", Up: []string{"CREATE TABLE `a` (`id` int)"}, Down: []string{"DROP TABLE `a`"}},
+	{Version: 2, Name: "create_b", Up: []string{"CREATE TABLE `b` (`id` int)"}, Down: []string{"DROP TABLE `b`"}},
+}
+
+func openSQLite(t *testing.T) (*gorm.DB, func()) {
+	dir, err := ioutil.TempDir("", "migration")
+	assert.NoError(t, err)
+	db, err := gorm.Open("sqlite3", filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func versions(t *testing.T, m *Migrator) map[uint64]bool {
+	status, err := m.Status(context.Background())
+	assert.NoError(t, err)
+	applied := make(map[uint64]bool)
+	for _, s := range status {
+		applied[s.Version] = s.Applied
+	}
+	return applied
+}
+
+func TestUpDown(t *testing.T) {
+	db, closeDB := openSQLite(t)
+	defer closeDB()
+	m := &Migrator{db: db, migrations: testMigrations}
+
+	// The status works before the migration table exists.
+	pending, err := m.Pending(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	done, err := m.Up()
+	assert.NoError(t, err)
+	assert.Len(t, done, 2)
+	assert.True(t, db.HasTable("a") && db.HasTable("b"))
+
+	// Nothing left to apply.
+	done, err = m.Up()
+	assert.NoError(t, err)
+	assert.Len(t, done, 0)
+
+	done, err = m.Down(1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), done[0].Version)
+	assert.False(t, db.HasTable("b"))
+	assert.Equal(t, map[uint64]bool{1: true, 2: false}, versions(t, m))
+}
+
+func TestDirty(t *testing.T) {
+	db, closeDB := openSQLite(t)
+	defer closeDB()
+
+	broken := Migration{Version: 3, Name: "broken", Up: []string{
+		"CREATE TABLE `c` (`id` int)",
+		"INSERT INTO `missing` VALUES (1)",
+	}, Down: []string{"DROP TABLE `c`"}}
+	m := &Migrator{db: db, migrations: append(testMigrations[:2:2], broken)}
+
+	done, err := m.Up()
+	assert.Error(t, err)
+	assert.Len(t, done, 2)
+	// The statements are rolled back, the version stays dirty.
+	assert.False(t, db.HasTable("c"))
+	status, err := m.Status(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, status[2].Dirty)
+	assert.False(t, status[2].Applied)
+
+	// A dirty version blocks the migrations until it's forced.
+	_, err = m.Up()
+	assert.Contains(t, err.Error(), "dirty")
+	_, err = m.Down(1)
+	assert.Contains(t, err.Error(), "dirty")
+
+	assert.Error(t, m.Force(1, true), "version 1 isn't dirty")
+	assert.NoError(t, m.Force(3, false))
+
+	// The fixed migration is applied.
+	m.migrations[2].Up = []string{"CREATE TABLE `c` (`id` int)"}
+	done, err = m.Up()
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.True(t, db.HasTable("c"))
+
+	// Forcing a dirty version as applied.
+	assert.NoError(t, db.Exec("UPDATE `"+tableName+"` SET `dirty` = 1 WHERE `version` = 3").Error)
+	assert.NoError(t, m.Force(3, true))
+	assert.Equal(t, map[uint64]bool{1: true, 2: true, 3: true}, versions(t, m))
+}
+
+func TestConcurrentUp(t *testing.T) {
+	db, closeDB := openSQLite(t)
+	defer closeDB()
+
+	// Each migration is applied by only one of the migrators.
+	var wg sync.WaitGroup
+	applied := make(chan int, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done, err := (&Migrator{db: db, migrations: testMigrations}).Up()
+			assert.NoError(t, err)
+			applied <- len(done)
+		}()
+	}
+	wg.Wait()
+	close(applied)
+
+	total := 0
+	for n := range applied {
+		total += n
+	}
+	assert.Equal(t, 2, total)
+}
+
+// TestMySQLLock needs a MySQL database, e.g.
+// APISERVER_TEST_MYSQL="root:123456@tcp(127.0.0.1:3306)/db_apiserver_test?parseTime=true"
+func TestMySQLLock(t *testing.T) {
+	dsn := os.Getenv("APISERVER_TEST_MYSQL")
+	if dsn == "" {
+		t.Skip("APISERVER_TEST_MYSQL is not set")
+	}
+	db, err := gorm.Open("mysql", dsn)
+	assert.NoError(t, err)
+	defer db.Close()
+	defer db.Exec("DROP TABLE IF EXISTS `a`, `b`, `" + tableName + "`")
+
+	// Another instance holds the lock.
+	conn, err := db.DB().Conn(context.Background())
+	assert.NoError(t, err)
+	defer conn.Close()
+	var locked sql.NullInt64
+	assert.NoError(t, conn.QueryRowContext(context.Background(), "SELECT GET_LOCK(?, 0)", lockName).Scan(&locked))
+	assert.Equal(t, int64(1), locked.Int64)
+
+	defer func(timeout int) { lockTimeout = timeout }(lockTimeout)
+	lockTimeout = 1
+	m := &Migrator{db: db, migrations: testMigrations}
+	start := time.Now()
+	_, err = m.Up()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout waiting for the lock")
+	assert.True(t, time.Since(start) >= time.Second)
+
+	// The lock is released.
+	assert.NoError(t, conn.QueryRowContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName).Scan(&locked))
+	done, err := m.Up()
+	assert.NoError(t, err)
+	assert.Len(t, done, 2)
+
+	done, err = m.Down(2)
+	assert.NoError(t, err)
+	assert.Len(t, done, 2)
+}
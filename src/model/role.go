@@ -0,0 +1,63 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// RoleModel represents a named set of permissions.
+type RoleModel struct {
+	BaseModel
+	Name        string                `json:"name" gorm:"column:name;not null" binding:"required" validate:"min=1,max=32"`
+	Description string                `json:"description" gorm:"column:description"`
+	Permissions []RolePermissionModel `json:"permissions" gorm:"foreignkey:RoleID" validate:"dive"`
+}
+
+func (r *RoleModel) TableName() string {
+	return "tb_roles"
+}
+
+// Can reports whether the role grants the permission.
+func (r *RoleModel) Can(permission string) bool {
+	for _, p := range r.Permissions {
+		if p.Match(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissionModel represents a permission granted to a role.
+type RolePermissionModel struct {
+	ID         uint64 `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"-"`
+	RoleID     uint64 `gorm:"column:roleId;not null" json:"-"`
+	Permission string `gorm:"column:permission;not null" json:"permission" validate:"permission"`
+}
+
+func (p *RolePermissionModel) TableName() string {
+	return "tb_role_permissions"
+}
+
+// Match reports whether the granted permission covers the required one,
+// `*` covers all the permissions and `users:*` covers all the actions of users.
+func (p *RolePermissionModel) Match(permission string) bool {
+	if p.Permission == "*" || p.Permission == permission {
+		return true
+	}
+	if strings.HasSuffix(p.Permission, ":*") {
+		return strings.HasPrefix(permission, strings.TrimSuffix(p.Permission, "*"))
+	}
+	return false
+}
+
+// UserRoleModel represents a role assigned to a user.
+type UserRoleModel struct {
+	ID        uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"-"`
+	UserID    uint64    `gorm:"column:userId;not null" json:"userId"`
+	RoleID    uint64    `gorm:"column:roleId;not null" json:"roleId"`
+	CreatedAt time.Time `gorm:"column:createdAt" json:"-"`
+}
+
+func (ur *UserRoleModel) TableName() string {
+	return "tb_user_roles"
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleCan(t *testing.T) {
+	assert := assert.New(t)
+	role := &RoleModel{
+		Name: "test",
+		Permissions: []RolePermissionModel{
+			{Permission: "users:*"},
+			{Permission: "roles:list"},
+		},
+	}
+
+	assert.True(role.Can("users:delete"))
+	assert.True(role.Can("roles:list"))
+	assert.False(role.Can("roles:manage"))
+	assert.False(role.Can("usersx:delete"))
+
+	admin := &RoleModel{Permissions: []RolePermissionModel{{Permission: "*"}}}
+	assert.True(admin.Can("roles:manage"))
+}
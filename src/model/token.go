@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+// RefreshTokenModel represents an issued refresh token, only the hash of the token is stored.
+type RefreshTokenModel struct {
+	BaseModel
+	UserID    uint64     `json:"userId" gorm:"column:userId;not null"`
+	Family    string     `json:"family" gorm:"column:family;not null"`
+	TokenHash string     `json:"-" gorm:"column:tokenHash;not null"`
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"column:expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt" gorm:"column:revokedAt"`
+}
+
+func (t *RefreshTokenModel) TableName() string {
+	return "tb_refresh_tokens"
+}
+
+// Revoked reports whether the token has been rotated or revoked.
+func (t *RefreshTokenModel) Revoked() bool {
+	return t.RevokedAt != nil
+}
+
+// Expired reports whether the token is expired.
+func (t *RefreshTokenModel) Expired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
@@ -0,0 +1,169 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/lexkong/log"
+)
+
+// Loader serves the certificate of the key pair files through tls.Config.GetCertificate,
+// and reloads it when the files change, e.g. renewed by cert-manager or certbot.
+type Loader struct {
+	certPath string
+	keyPath  string
+
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	notAfter time.Time
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// NewLoader loads the key pair, it fails if the initial pair is invalid.
+func NewLoader(certPath, keyPath string) (*Loader, error) {
+	l := &Loader{
+		certPath: certPath,
+		keyPath:  keyPath,
+		done:     make(chan struct{}),
+	}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetCertificate returns the current certificate.
+func (l *Loader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.cert, nil
+}
+
+// NotAfter returns the expiry date of the current certificate.
+func (l *Loader) NotAfter() time.Time {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.notAfter
+}
+
+// Reload loads the key pair and swaps the certificate,
+// the current certificate is kept if the new pair is invalid.
+func (l *Loader) Reload() error {
+	l.mutex.RLock()
+	certPath, keyPath := l.certPath, l.keyPath
+	l.mutex.RUnlock()
+
+	return l.load(certPath, keyPath)
+}
+
+// SetPaths switches to another key pair, the current one is kept if the new pair is invalid.
+func (l *Loader) SetPaths(certPath, keyPath string) error {
+	if err := l.load(certPath, keyPath); err != nil {
+		return err
+	}
+
+	if l.watcher != nil {
+		for _, dir := range []string{filepath.Dir(certPath), filepath.Dir(keyPath)} {
+			if err := l.watcher.Add(dir); err != nil {
+				log.Errorf(err, "Watch the TLS certificate directory %s failed.", dir)
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Loader) load(certPath, keyPath string) error {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return err
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	if time.Now().After(leaf.NotAfter) {
+		return errors.New("the certificate has expired at " + leaf.NotAfter.Format(time.RFC3339))
+	}
+	cert.Leaf = leaf
+
+	l.mutex.Lock()
+	l.certPath, l.keyPath = certPath, keyPath
+	l.cert = &cert
+	l.notAfter = leaf.NotAfter
+	l.mutex.Unlock()
+
+	log.Infof("TLS certificate %s loaded, expires at %s", certPath, leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+// Watch reloads the certificate when the key pair files change.
+// The directories are watched, as the files are often replaced by renaming or symlink swaps.
+func (l *Loader) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dirs := map[string]bool{
+		filepath.Dir(l.certPath): true,
+		filepath.Dir(l.keyPath):  true,
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+	l.watcher = watcher
+
+	go l.watch()
+	return nil
+}
+
+func (l *Loader) watch() {
+	defer close(l.done)
+
+	// Wait for the cert and key files to be both written.
+	var reload <-chan time.Time
+
+	for {
+		select {
+		case _, ok := <-l.watcher.Events:
+			if !ok {
+				return
+			}
+			reload = time.After(500 * time.Millisecond)
+		case err, ok := <-l.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf(err, "Watch the TLS certificate failed.")
+		case <-reload:
+			reload = nil
+			if err := l.Reload(); err != nil {
+				log.Errorf(err, "Reload the TLS certificate failed, keep serving the previous one.")
+			}
+		}
+	}
+}
+
+// Close stops watching the files.
+func (l *Loader) Close() error {
+	if l.watcher == nil {
+		return nil
+	}
+
+	err := l.watcher.Close()
+	<-l.done
+	return err
+}
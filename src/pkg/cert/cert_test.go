@@ -0,0 +1,41 @@
+package cert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func copyFile(t *testing.T, src, dst string) {
+	data, err := ioutil.ReadFile(src)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(dst, data, 0600))
+}
+
+func TestReloadKeepsPreviousCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "server.crt")
+	keyPath := filepath.Join(dir, "server.key")
+	copyFile(t, "../../config/server.crt", certPath)
+	copyFile(t, "../../config/server.key", keyPath)
+
+	l, err := NewLoader(certPath, keyPath)
+	assert.Nil(t, err)
+	notAfter := l.NotAfter()
+	assert.False(t, notAfter.IsZero())
+
+	// A broken key pair is rejected.
+	assert.Nil(t, ioutil.WriteFile(keyPath, []byte("broken"), 0600))
+	assert.NotNil(t, l.Reload())
+
+	c, err := l.GetCertificate(nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+	assert.Equal(t, notAfter, l.NotAfter())
+}
@@ -0,0 +1,56 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// ClientAuthType returns the tls.ClientAuthType of the mode: none, request or require.
+// The client certificate is verified against the client CA in both request and require modes.
+func ClientAuthType(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("unknown client auth mode %q", mode)
+}
+
+// LoadClientCAs loads the PEM encoded CA bundle used to verify the client certificates.
+func LoadClientCAs(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no certificate found in the client CA bundle " + path)
+	}
+	return pool, nil
+}
+
+// Identity returns the identity of the client certificate,
+// from the subject common name or the first SAN (DNS name, email or URI).
+func Identity(c *x509.Certificate, source string) string {
+	if source != "san" {
+		return c.Subject.CommonName
+	}
+
+	if len(c.DNSNames) > 0 {
+		return c.DNSNames[0]
+	}
+	if len(c.EmailAddresses) > 0 {
+		return c.EmailAddresses[0]
+	}
+	if len(c.URIs) > 0 {
+		return c.URIs[0].String()
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientAuthType(t *testing.T) {
+	for mode, want := range map[string]tls.ClientAuthType{
+		"":        tls.NoClientCert,
+		"none":    tls.NoClientCert,
+		"request": tls.VerifyClientCertIfGiven,
+		"require": tls.RequireAndVerifyClientCert,
+	} {
+		got, err := ClientAuthType(mode)
+		assert.Nil(t, err)
+		assert.Equal(t, want, got, mode)
+	}
+
+	_, err := ClientAuthType("optional")
+	assert.NotNil(t, err)
+}
+
+func TestIdentity(t *testing.T) {
+	u, _ := url.Parse("spiffe://example.com/billing")
+	c := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "billing"},
+		DNSNames:       []string{"billing.example.com"},
+		EmailAddresses: []string{"billing@example.com"},
+		URIs:           []*url.URL{u},
+	}
+
+	assert.Equal(t, "billing", Identity(c, "cn"))
+	assert.Equal(t, "billing", Identity(c, ""))
+
+	// The SANs by order: DNS name, email then URI.
+	assert.Equal(t, "billing.example.com", Identity(c, "san"))
+	c.DNSNames = nil
+	assert.Equal(t, "billing@example.com", Identity(c, "san"))
+	c.EmailAddresses = nil
+	assert.Equal(t, "spiffe://example.com/billing", Identity(c, "san"))
+	c.URIs = nil
+	assert.Equal(t, "", Identity(c, "san"))
+}
@@ -0,0 +1,38 @@
+package constvar
+
+import "strings"
+
+// 权限, 格式为 `resource:action`, `*` 匹配所有权限, `users:*` 匹配 users 的所有操作
+const (
+	PermAll = "*"
+
+	PermUsersCreate = "users:create"
+	PermUsersGet    = "users:get"
+	PermUsersList   = "users:list"
+	PermUsersUpdate = "users:update"
+	PermUsersDelete = "users:delete"
+
+	PermRolesList   = "roles:list"
+	PermRolesManage = "roles:manage"
+)
+
+// Permissions are the permissions checked by the routes.
+var Permissions = []string{
+	PermUsersCreate, PermUsersGet, PermUsersList, PermUsersUpdate, PermUsersDelete,
+	PermRolesList, PermRolesManage,
+}
+
+// ValidPermission reports whether the permission is `*`, a known permission,
+// or the wildcard of a known resource, e.g. `users:*`.
+func ValidPermission(permission string) bool {
+	if permission == PermAll {
+		return true
+	}
+	for _, p := range Permissions {
+		resource := p[:strings.Index(p, ":")+1]
+		if permission == p || permission == resource+"*" {
+			return true
+		}
+	}
+	return false
+}
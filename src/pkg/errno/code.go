@@ -1,21 +1,60 @@
 package errno
 
+import "net/http"
+
 var (
 	// Common errors
 	// ------------------------------------------
 	// 成功
-	OK 	= &Errno{Code: 0, Message: "OK"}
+	OK = Register(&Errno{Code: 0, Message: "OK", HTTPStatus: http.StatusOK, Module: "common",
+		Description: "The request succeeded."})
 	// 服务器错误
-	InternalServerError = &Errno{Code: 10001, Message: "Internal server error"}
-	ErrBind             = &Errno{Code: 10002, Message: "Error occurred while binding the request body to the struct."}
+	InternalServerError = Register(&Errno{Code: 10001, Message: "Internal server error", HTTPStatus: http.StatusInternalServerError, Module: "common",
+		Description: "An unexpected error occurred on the server."})
+	ErrBind = Register(&Errno{Code: 10002, Message: "Error occurred while binding the request body to the struct.", HTTPStatus: http.StatusBadRequest, Module: "common",
+		Description: "The request body or the parameters can't be parsed."})
+	ErrToken = Register(&Errno{Code: 10003, Message: "Error occurred while signing the JSON web token.", HTTPStatus: http.StatusInternalServerError, Module: "common",
+		Description: "The server failed to sign the token."})
+	ErrTooManyRequests = Register(&Errno{Code: 10004, Message: "Too many requests, please try again later.", HTTPStatus: http.StatusTooManyRequests, Module: "common",
+		Description: "The global rate limit is exceeded."})
+	ErrRouteNotFound = Register(&Errno{Code: 10005, Message: "The requested API was not found.", HTTPStatus: http.StatusNotFound, Module: "common",
+		Description: "No API matches the method and the path."})
 
+	// 参数校验错误
+	ErrValidation = Register(&Errno{Code: 20001, Message: "Validation failed.", HTTPStatus: http.StatusBadRequest, Module: "common",
+		Description: "Some fields are invalid, the errors list the fields and the failed rules."})
 
 	// 数据库错误
-	ErrDatabase = &Errno{Code: 20002, Message: "Database error."}
-	ErrValidation = &Errno{Code: 20001, Message: "Validation failed."}
+	ErrDatabase = Register(&Errno{Code: 20002, Message: "Database error.", HTTPStatus: http.StatusInternalServerError, Module: "database",
+		Description: "The database query failed."})
 
 	// 用户错误
 	// --------------------------------------------
-	ErrEncrypt = &Err{Code: 20101, Message: "Error occurred while encrypting the user password."}
-	ErrUserNotFound = &Err{Code: 20102, Message: "The user was not found."}
-)
\ No newline at end of file
+	ErrEncrypt = Register(&Errno{Code: 20101, Message: "Error occurred while encrypting the user password.", HTTPStatus: http.StatusInternalServerError, Module: "user",
+		Description: "The server failed to hash the password."})
+	ErrUserNotFound = Register(&Errno{Code: 20102, Message: "The user was not found.", HTTPStatus: http.StatusNotFound, Module: "user",
+		Description: "No user has the id or the username."})
+	ErrTokenInvalid = Register(&Errno{Code: 20103, Message: "The token was invalid.", HTTPStatus: http.StatusUnauthorized, Module: "user",
+		Description: "The token is missing, malformed or signed with another secret."})
+	ErrPasswordIncorrect = Register(&Errno{Code: 20104, Message: "The password was incorrect.", HTTPStatus: http.StatusUnauthorized, Module: "user",
+		Description: "The password doesn't match the user."})
+	ErrTokenExpired = Register(&Errno{Code: 20105, Message: "The token was expired.", HTTPStatus: http.StatusUnauthorized, Module: "user",
+		Description: "The token is expired, refresh it with the refresh token."})
+	ErrTokenReused = Register(&Errno{Code: 20106, Message: "The refresh token was reused, the session has been revoked.", HTTPStatus: http.StatusUnauthorized, Module: "user",
+		Description: "The refresh token was used before, all the tokens of the session are revoked."})
+	ErrSessionNotFound = Register(&Errno{Code: 20107, Message: "The session was not found.", HTTPStatus: http.StatusNotFound, Module: "user",
+		Description: "The session doesn't exist or belongs to another user."})
+	ErrCertificateInvalid = Register(&Errno{Code: 20108, Message: "The client certificate was not mapped to any user.", HTTPStatus: http.StatusUnauthorized, Module: "user",
+		Description: "The identity of the client certificate isn't a user."})
+	ErrCredentialsInvalid = Register(&Errno{Code: 20109, Message: "The username or password was incorrect.", HTTPStatus: http.StatusUnauthorized, Module: "user",
+		Description: "The login failed, the username doesn't exist or the password doesn't match."})
+
+	// 角色权限错误
+	// --------------------------------------------
+	ErrPermissionDenied = Register(&Errno{Code: 20201, Message: "Permission denied.", HTTPStatus: http.StatusForbidden, Module: "role",
+		Description: "The roles of the user don't grant the permission."})
+	ErrRoleNotFound = Register(&Errno{Code: 20202, Message: "The role was not found.", HTTPStatus: http.StatusNotFound, Module: "role",
+		Description: "No role has the name."})
+	ErrRoleExists = Register(&Errno{Code: 20203, Message: "The role already exists.", HTTPStatus: http.StatusConflict, Module: "role",
+		Description: "Another role has the name."})
+)
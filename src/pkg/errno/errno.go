@@ -1,10 +1,19 @@
 package errno
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
+// Errno is the code and the message of the response, HTTPStatus is the status code of the response.
+// Module and Description document the code in the error catalog.
 type Errno struct {
-	Code    int
-	Message string
+	Code        int
+	Message     string
+	HTTPStatus  int
+	Module      string
+	Description string
 }
 
 func (err Errno) Error() string {
@@ -13,30 +22,45 @@ func (err Errno) Error() string {
 
 // Err represents an error
 type Err struct {
-	Code    int
-	Message string
-	Err     error
+	Code       int
+	Message    string
+	HTTPStatus int
+	Err        error
+
+	// extra is the message added to the message of the errno, it's kept in the translations.
+	extra string
 }
 
 // 新建定制错误
 func New(errno *Errno, err error) *Err {
-	return &Err{Code: errno.Code, Message: errno.Message, Err: err}
+	return &Err{Code: errno.Code, Message: errno.Message, HTTPStatus: errno.HTTPStatus, Err: err}
 }
 
 func (err *Err) Add(message string) error {
 	err.Message += " " + message
+	err.extra += " " + message
 	return err
 }
 
 func (err *Err) Addf(format string, args ...interface{}) error {
-	err.Message += " " + fmt.Sprintf(format, args...)
-	return err
+	return err.Add(fmt.Sprintf(format, args...))
 }
 
 func (err *Err) Error() string {
 	return fmt.Sprintf("Err - code: %d, message: %s, error: %s", err.Code, err.Message, err.Err)
 }
 
+// Unwrap returns the underlying error, e.g. the validator.ValidationErrors of ErrValidation.
+func (err *Err) Unwrap() error {
+	return err.Err
+}
+
+// Is reports whether the error is created from the errno, e.g. errors.Is(err, errno.ErrValidation).
+func (err *Err) Is(target error) bool {
+	t, ok := target.(*Errno)
+	return ok && t.Code == err.Code
+}
+
 func IsErrUserNotFound(err error) bool {
 	code, _ := DecodeErr(err)
 	return code == ErrUserNotFound.Code
@@ -48,13 +72,32 @@ func DecodeErr(err error) (int, string) {
 		return OK.Code, OK.Message
 	}
 
-	switch typed := err.(type) {
-	case *Err:
-		return typed.Code, typed.Message
-	case *Errno:
-		return typed.Code, typed.Message
-	default:
+	var e *Err
+	if errors.As(err, &e) {
+		return e.Code, e.Message
+	}
+	var no *Errno
+	if errors.As(err, &no) {
+		return no.Code, no.Message
 	}
 
 	return InternalServerError.Code, err.Error()
 }
+
+// HTTPStatus returns the status code of the response of the error, the unknown errors are 500.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	code, _ := DecodeErr(err)
+	var e *Err
+	if errors.As(err, &e) && e.HTTPStatus != 0 {
+		return e.HTTPStatus
+	}
+	if no, ok := Lookup(code); ok && no.HTTPStatus != 0 {
+		return no.HTTPStatus
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,44 @@
+package errno
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	for _, e := range All() {
+		assert.NotZero(t, e.HTTPStatus, "code %d", e.Code)
+		assert.NotEmpty(t, e.Module, "code %d", e.Code)
+
+		// The messages in code are the default language, every other language translates them all.
+		for _, lang := range Languages()[1:] {
+			assert.Contains(t, messages[lang], e.Code, "code %d isn't translated into %s", e.Code, lang)
+		}
+	}
+
+	assert.Panics(t, func() {
+		Register(&Errno{Code: ErrUserNotFound.Code, Message: "Another error."})
+	})
+}
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("duplicate entry")
+	err := fmt.Errorf("create user: %w", New(ErrDatabase, cause))
+
+	assert.True(t, errors.Is(err, ErrDatabase))
+	assert.True(t, errors.Is(err, cause))
+	assert.False(t, errors.Is(err, ErrUserNotFound))
+
+	var e *Err
+	assert.True(t, errors.As(err, &e))
+	code, message := DecodeErr(err)
+	assert.Equal(t, ErrDatabase.Code, code)
+	assert.Equal(t, ErrDatabase.Message, message)
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatus(err))
+
+	assert.Equal(t, http.StatusNotFound, HTTPStatus(fmt.Errorf("get user: %w", ErrUserNotFound)))
+}
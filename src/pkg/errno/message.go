@@ -0,0 +1,75 @@
+package errno
+
+import "errors"
+
+// DefaultLanguage is the language of the messages in code.go.
+const DefaultLanguage = "en"
+
+// messages are the translations of the messages, keyed by the language and the code of the errno.
+var messages = map[string]map[int]string{
+	"zh-CN": {
+		OK.Code:                    "成功",
+		InternalServerError.Code:   "服务器内部错误。",
+		ErrBind.Code:               "请求参数绑定失败。",
+		ErrToken.Code:              "签发 JSON Web Token 失败。",
+		ErrTooManyRequests.Code:    "请求过于频繁，请稍后再试。",
+		ErrRouteNotFound.Code:      "不存在的接口地址。",
+		ErrValidation.Code:         "参数校验失败。",
+		ErrDatabase.Code:           "数据库错误。",
+		ErrEncrypt.Code:            "加密用户密码失败。",
+		ErrUserNotFound.Code:       "用户不存在。",
+		ErrTokenInvalid.Code:       "无效的 token。",
+		ErrPasswordIncorrect.Code:  "密码错误。",
+		ErrTokenExpired.Code:       "token 已过期。",
+		ErrTokenReused.Code:        "refresh token 被重复使用，会话已被注销。",
+		ErrSessionNotFound.Code:    "会话不存在。",
+		ErrCertificateInvalid.Code: "客户端证书没有对应的用户。",
+		ErrCredentialsInvalid.Code: "用户名或密码错误。",
+		ErrPermissionDenied.Code:   "没有权限。",
+		ErrRoleNotFound.Code:       "角色不存在。",
+		ErrRoleExists.Code:         "角色已存在。",
+	},
+}
+
+// Languages returns the supported languages, the default one first.
+func Languages() []string {
+	return []string{DefaultLanguage, "zh-CN"}
+}
+
+// Translate returns the message of the code in the language, or the message itself if it isn't translated.
+func Translate(code int, message, lang string) string {
+	if m, ok := messages[lang][code]; ok {
+		return m
+	}
+	return message
+}
+
+// DecodeErrIn is DecodeErr with the message translated into the language.
+func DecodeErrIn(err error, lang string) (int, string) {
+	if err == nil {
+		return OK.Code, Translate(OK.Code, OK.Message, lang)
+	}
+
+	var e *Err
+	if errors.As(err, &e) {
+		if m, ok := messages[lang][e.Code]; ok {
+			return e.Code, m + e.extra
+		}
+		return e.Code, e.Message
+	}
+	var no *Errno
+	if errors.As(err, &no) {
+		return no.Code, Translate(no.Code, no.Message, lang)
+	}
+
+	return DecodeErr(err)
+}
+
+// Messages returns the message of the errno in each supported language.
+func Messages(errno *Errno) map[string]string {
+	m := make(map[string]string, len(Languages()))
+	for _, lang := range Languages() {
+		m[lang] = Translate(errno.Code, errno.Message, lang)
+	}
+	return m
+}
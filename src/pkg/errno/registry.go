@@ -0,0 +1,49 @@
+package errno
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	mutex    sync.RWMutex
+	registry = make(map[int]*Errno)
+)
+
+// Register adds the errno to the catalog, it panics if the code is registered already,
+// so the colliding codes fail the startup and the tests.
+func Register(errno *Errno) *Errno {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if e, ok := registry[errno.Code]; ok {
+		panic(fmt.Sprintf("errno: duplicate code %d of %q and %q", errno.Code, e.Message, errno.Message))
+	}
+	registry[errno.Code] = errno
+	return errno
+}
+
+// Lookup returns the registered errno of the code.
+func Lookup(code int) (*Errno, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	errno, ok := registry[code]
+	return errno, ok
+}
+
+// All returns the registered errnos ordered by code.
+func All() []*Errno {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	all := make([]*Errno, 0, len(registry))
+	for _, errno := range registry {
+		all = append(all, errno)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Code < all[j].Code
+	})
+	return all
+}
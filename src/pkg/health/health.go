@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	// 单个检查的超时时间
+	checkTimeout = 2 * time.Second
+)
+
+// HealthChecker checks a dependency the server needs to serve the requests, e.g. the database.
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the HealthChecker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls fn(ctx).
+func (fn CheckerFunc) Check(ctx context.Context) error {
+	return fn(ctx)
+}
+
+// Result is the result of a check, the last error is kept after the check recovers.
+type Result struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Latency     string     `json:"latency"`
+	Error       string     `json:"error,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Report is the result of all the checks.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// OK reports whether all the checks passed.
+func (r *Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type entry struct {
+	checker     HealthChecker
+	lastError   string
+	lastErrorAt *time.Time
+}
+
+var (
+	mutex    sync.Mutex
+	checkers = make(map[string]*entry)
+)
+
+// Register adds the checker of the readiness, the checker of the same name is replaced.
+func Register(name string, checker HealthChecker) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	checkers[name] = &entry{checker: checker}
+}
+
+// Unregister removes the checker.
+func Unregister(name string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delete(checkers, name)
+}
+
+// Run runs the registered checks concurrently, the results are ordered by name.
+func Run(ctx context.Context) *Report {
+	mutex.Lock()
+	names := make([]string, 0, len(checkers))
+	entries := make([]*entry, 0, len(checkers))
+	for name, e := range checkers {
+		names = append(names, name)
+		entries = append(entries, e)
+	}
+	mutex.Unlock()
+
+	results := make([]Result, len(entries))
+	var wg sync.WaitGroup
+	for i := range entries {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = run(ctx, names[i], entries[i])
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	report := &Report{Status: StatusOK, Checks: results}
+	for _, r := range results {
+		if r.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, name string, e *entry) Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := e.checker.Check(ctx)
+	latency := time.Since(start)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	r := Result{
+		Name:    name,
+		Status:  StatusOK,
+		Latency: latency.String(),
+	}
+	if err != nil {
+		now := start.UTC()
+		e.lastError, e.lastErrorAt = err.Error(), &now
+		r.Status = StatusFail
+		r.Error = err.Error()
+	}
+	r.LastError, r.LastErrorAt = e.lastError, e.lastErrorAt
+	return r
+}
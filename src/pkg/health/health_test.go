@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	var err error
+	Register("b", CheckerFunc(func(ctx context.Context) error { return nil }))
+	Register("a", CheckerFunc(func(ctx context.Context) error { return err }))
+	defer Unregister("a")
+	defer Unregister("b")
+
+	report := Run(context.Background())
+	assert.True(t, report.OK())
+	assert.Equal(t, "a", report.Checks[0].Name)
+
+	err = errors.New("connection refused")
+	report = Run(context.Background())
+	assert.False(t, report.OK())
+	assert.Equal(t, StatusFail, report.Checks[0].Status)
+	assert.Equal(t, "connection refused", report.Checks[0].Error)
+	assert.Equal(t, StatusOK, report.Checks[1].Status)
+
+	// The last error is kept after the check recovers.
+	err = nil
+	report = Run(context.Background())
+	assert.True(t, report.OK())
+	assert.Equal(t, "", report.Checks[0].Error)
+	assert.Equal(t, "connection refused", report.Checks[0].LastError)
+	assert.NotNil(t, report.Checks[0].LastErrorAt)
+}
@@ -0,0 +1,128 @@
+package shutdown
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/lexkong/log"
+)
+
+// Phase orders the shutdown hooks, the hooks of the same phase run concurrently.
+type Phase int
+
+const (
+	// 停止接收请求, 等待处理中的请求结束
+	PhaseHTTP Phase = iota
+	// 停止后台任务
+	PhaseWorker
+	// 关闭数据库等外部连接
+	PhaseDB
+)
+
+// Hook releases a resource, it should return once the ctx is done.
+type Hook func(ctx context.Context) error
+
+type hook struct {
+	phase Phase
+	name  string
+	fn    Hook
+}
+
+var (
+	mutex    sync.Mutex
+	hooks    []hook
+	draining int32
+	once     sync.Once
+	done     = make(chan struct{})
+
+	// exit 测试时替换
+	exit = os.Exit
+)
+
+// Register adds the hook which runs at the phase of the shutdown.
+func Register(phase Phase, name string, fn Hook) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	hooks = append(hooks, hook{phase: phase, name: name, fn: fn})
+}
+
+// Draining reports whether the shutdown has started, the readiness check fails since then.
+func Draining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
+
+// Done is closed when all the hooks finished.
+func Done() <-chan struct{} {
+	return done
+}
+
+// Notify shuts down on SIGINT, SIGQUIT or SIGTERM, and exits immediately on the second signal.
+func Notify(timeout, delay time.Duration) {
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+
+	go wait(c, timeout, delay)
+}
+
+func wait(c <-chan os.Signal, timeout, delay time.Duration) {
+	s := <-c
+	log.Infof("got signal [%s], shutting down apiserver, send it again to exit immediately", s)
+	go Shutdown(timeout, delay)
+
+	s = <-c
+	log.Infof("got signal [%s] again, exiting apiserver now", s)
+	exit(1)
+}
+
+// Shutdown flips the readiness to failing, waits the delay for the load balancers to notice it,
+// then runs the hooks phase by phase within the timeout. It only runs once.
+func Shutdown(timeout, delay time.Duration) {
+	once.Do(func() {
+		defer close(done)
+
+		atomic.StoreInt32(&draining, 1)
+		if delay > 0 {
+			log.Infof("readiness is failing, waiting %s before draining", delay)
+			time.Sleep(delay)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		mutex.Lock()
+		sorted := make([]hook, len(hooks))
+		copy(sorted, hooks)
+		mutex.Unlock()
+
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].phase < sorted[j].phase
+		})
+
+		for i := 0; i < len(sorted); {
+			j := i
+			var wg sync.WaitGroup
+			for ; j < len(sorted) && sorted[j].phase == sorted[i].phase; j++ {
+				wg.Add(1)
+				go func(h hook) {
+					defer wg.Done()
+					if err := h.fn(ctx); err != nil {
+						log.Errorf(err, "shutdown %s failed", h.name)
+						return
+					}
+					log.Infof("shutdown %s succeed", h.name)
+				}(sorted[j])
+			}
+			wg.Wait()
+			i = j
+		}
+
+		log.Infof("apiserver exited")
+	})
+}
@@ -0,0 +1,105 @@
+package shutdown
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reset() {
+	hooks = nil
+	draining = 0
+	once = sync.Once{}
+	done = make(chan struct{})
+}
+
+func TestShutdownPhases(t *testing.T) {
+	reset()
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) Hook {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+	// Registered out of order.
+	Register(PhaseDB, "db", record("db"))
+	Register(PhaseWorker, "worker", record("worker"))
+	Register(PhaseHTTP, "http", record("http"))
+
+	assert.False(t, Draining())
+	Shutdown(time.Second, 0)
+	assert.True(t, Draining())
+	assert.Equal(t, []string{"http", "worker", "db"}, order)
+
+	select {
+	case <-Done():
+	default:
+		t.Fatal("done is not closed")
+	}
+
+	// It only runs once.
+	Shutdown(time.Second, 0)
+	assert.Len(t, order, 3)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	reset()
+
+	var dbErr error
+	Register(PhaseHTTP, "http", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	Register(PhaseDB, "db", func(ctx context.Context) error {
+		dbErr = ctx.Err()
+		return nil
+	})
+
+	start := time.Now()
+	Shutdown(50*time.Millisecond, 0)
+	assert.True(t, time.Since(start) < time.Second)
+	// The later phases still run, with the expired ctx.
+	assert.Equal(t, context.DeadlineExceeded, dbErr)
+}
+
+func TestSecondSignalExits(t *testing.T) {
+	reset()
+
+	block := make(chan struct{})
+	Register(PhaseHTTP, "http", func(ctx context.Context) error {
+		<-block
+		return nil
+	})
+
+	exited := make(chan int, 1)
+	exit = func(code int) { exited <- code }
+	defer func() { exit = os.Exit }()
+
+	c := make(chan os.Signal, 2)
+	go wait(c, time.Minute, 0)
+
+	c <- syscall.SIGTERM
+	assert.Eventually(t, Draining, time.Second, 10*time.Millisecond)
+
+	c <- syscall.SIGINT
+	select {
+	case code := <-exited:
+		assert.Equal(t, 1, code)
+	case <-time.After(time.Second):
+		t.Fatal("the second signal didn't exit")
+	}
+
+	// Shutdown waits for the running one.
+	close(block)
+	Shutdown(time.Second, 0)
+}
@@ -0,0 +1,137 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/pkg/errno"
+)
+
+// Context is the context of the JSON web token.
+type Context struct {
+	ID       uint64
+	Username string
+}
+
+// secretFunc validates the secret format.
+func secretFunc(secret string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		// Make sure the `alg` is what we expect.
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+
+		return []byte(secret), nil
+	}
+}
+
+// Parse validates the token with the specified secret,
+// and returns the context if the token was valid.
+func Parse(tokenString string, secret string) (*Context, error) {
+	ctx := &Context{}
+
+	// Parse the token.
+	token, err := jwt.Parse(tokenString, secretFunc(secret))
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Errors&jwt.ValidationErrorExpired != 0 {
+			return ctx, errno.ErrTokenExpired
+		}
+		return ctx, errno.ErrTokenInvalid
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return ctx, errno.ErrTokenInvalid
+	}
+
+	id, ok := claims["id"].(float64)
+	if !ok {
+		return ctx, errno.ErrTokenInvalid
+	}
+	ctx.ID = uint64(id)
+	ctx.Username, _ = claims["username"].(string)
+
+	return ctx, nil
+}
+
+// ParseRequest gets the token from the `Authorization: Bearer` header,
+// and pass it to the Parse function to parses the token.
+func ParseRequest(c *gin.Context) (*Context, error) {
+	header := c.Request.Header.Get("Authorization")
+
+	// Load the jwt secret from config
+	secret, _ := settings()
+
+	// 没有密钥时任何人都能签发 token
+	if len(header) == 0 || secret == "" {
+		return &Context{}, errno.ErrTokenInvalid
+	}
+
+	var t string
+	// Parse the header to get the token part.
+	if _, err := fmt.Sscanf(header, "Bearer %s", &t); err != nil {
+		return &Context{}, errno.ErrTokenInvalid
+	}
+
+	return Parse(t, secret)
+}
+
+// Sign signs the context with the specified secret.
+// The secret and the expiration default to `core.jwt_secret` and `core.jwt_timeout`.
+func Sign(c Context, secret string) (tokenString string, err error) {
+	// Load the jwt secret from the config if the secret isn't specified.
+	defaultSecret, timeout := settings()
+	if secret == "" {
+		secret = defaultSecret
+	}
+	if secret == "" {
+		return "", errors.New("token: the jwt secret is empty")
+	}
+
+	now := time.Now()
+
+	// The token content.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":       c.ID,
+		"username": c.Username,
+		"nbf":      now.Unix(),
+		"iat":      now.Unix(),
+		"exp":      now.Add(timeout).Unix(),
+	})
+
+	// Sign the token with the specified secret.
+	tokenString, err = token.SignedString([]byte(secret))
+
+	return
+}
+
+// settings returns the jwt secret and timeout of the current config, which is swapped on reload.
+func settings() (string, time.Duration) {
+	if conf := config.Current(); conf != nil {
+		return conf.Core.JwtSecret, conf.Core.JwtTimeout
+	}
+	return "", 0
+}
+
+// NewOpaque returns a random opaque token, used as refresh token.
+func NewOpaque() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns the hex encoded sha256 of the opaque token.
+func Hash(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,43 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndParse(t *testing.T) {
+	config.Store(&config.ConfYaml{Core: config.SectionCore{JwtTimeout: time.Hour}})
+
+	tokenString, err := Sign(Context{ID: 1, Username: "admin"}, "secret")
+	assert.Nil(t, err)
+
+	ctx, err := Parse(tokenString, "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), ctx.ID)
+	assert.Equal(t, "admin", ctx.Username)
+
+	_, err = Parse(tokenString, "other")
+	assert.Equal(t, errno.ErrTokenInvalid, err)
+}
+
+func TestExpiredToken(t *testing.T) {
+	config.Store(&config.ConfYaml{Core: config.SectionCore{JwtTimeout: -time.Hour}})
+
+	tokenString, err := Sign(Context{ID: 1, Username: "admin"}, "secret")
+	assert.Nil(t, err)
+
+	_, err = Parse(tokenString, "secret")
+	assert.Equal(t, errno.ErrTokenExpired, err)
+}
+
+func TestEmptySecret(t *testing.T) {
+	config.Store(&config.ConfYaml{Core: config.SectionCore{JwtTimeout: time.Hour}})
+
+	// The tokens signed with an empty key are never accepted.
+	_, err := Sign(Context{ID: 1, Username: "admin"}, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,22 @@
+package src
+
+import (
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/router/middleware"
+	"github.com/moocss/apiserver/src/service"
+)
+
+// ApplyConfig applies the runtime settings, and applies them again when the config file changes.
+// The log and TLS settings are applied by the config package and RunHTTPServer.
+func ApplyConfig() {
+	apply(&Conf)
+	config.Subscribe(func(old, conf *config.ConfYaml) {
+		apply(conf)
+	})
+}
+
+func apply(conf *config.ConfYaml) {
+	middleware.SetCORSOrigins(conf.Core.CORS.AllowOrigins)
+	middleware.SetRateLimit(conf.Core.RateLimit.Enabled, conf.Core.RateLimit.Rps, conf.Core.RateLimit.Burst)
+	service.DB.Configure(&conf.Db)
+}
@@ -1 +1,65 @@
 package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/pkg/cert"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/pkg/token"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/moocss/apiserver/src/util"
+)
+
+// AuthMiddleware parses the `Authorization: Bearer` token, or the verified
+// client certificate in mutual TLS mode, and puts the user identity into the context.
+func AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var (
+			ctx *token.Context
+			err error
+		)
+
+		if c.Request.Header.Get("Authorization") == "" && hasClientCertificate(c) {
+			// Authenticate the backend callers by the client certificate.
+			ctx, err = parseClientCertificate(c)
+		} else {
+			// Parse the json web token.
+			ctx, err = token.ParseRequest(c)
+		}
+		if err != nil {
+			util.SendResponse(c, err, nil)
+			c.Abort()
+			return
+		}
+
+		// Expose the identity for use in the application
+		c.Set("X-User-Id", ctx.ID)
+		c.Set("X-Username", ctx.Username)
+		c.Next()
+	}
+}
+
+// hasClientCertificate reports whether the client presented a certificate verified against the client CA.
+func hasClientCertificate(c *gin.Context) bool {
+	return c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0
+}
+
+// parseClientCertificate maps the subject or SAN of the client certificate onto the user.
+func parseClientCertificate(c *gin.Context) (*token.Context, error) {
+	leaf := c.Request.TLS.VerifiedChains[0][0]
+	var identity string
+	if conf := config.Current(); conf != nil {
+		identity = conf.Core.TLS.ClientIdentity
+	}
+	username := cert.Identity(leaf, identity)
+	if username == "" {
+		return nil, errno.ErrCertificateInvalid
+	}
+
+	u := service.User.WithContext(c.Request.Context()).GetUserByName(username)
+	if u == nil {
+		return nil, errno.ErrCertificateInvalid
+	}
+
+	return &token.Context{ID: u.ID, Username: u.Username}, nil
+}
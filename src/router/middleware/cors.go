@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsOrigins holds the []string of the origins allowed to access the api.
+var corsOrigins atomic.Value
+
+// SetCORSOrigins changes the allowed origins, "*" allows all.
+func SetCORSOrigins(origins []string) {
+	corsOrigins.Store(append([]string(nil), origins...))
+}
+
+// allowOrigin appends the Access-Control-Allow-Origin header if the origin is allowed.
+func allowOrigin(c *gin.Context) {
+	origins, _ := corsOrigins.Load().([]string)
+	origin := c.GetHeader("Origin")
+
+	for _, o := range origins {
+		if o == "*" {
+			c.Header("Access-Control-Allow-Origin", "*")
+			return
+		}
+	}
+	// 响应随 Origin 变化, 不允许的 Origin 也要告诉缓存
+	if len(origins) > 0 {
+		c.Writer.Header().Add("Vary", "Origin")
+	}
+	for _, o := range origins {
+		if origin != "" && o == origin {
+			c.Header("Access-Control-Allow-Origin", origin)
+			return
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.Use(Secure)
+	g.GET("/", func(c *gin.Context) {})
+	defer SetCORSOrigins(nil)
+
+	request := func(origin string) http.Header {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		return w.Header()
+	}
+
+	SetCORSOrigins([]string{"https://a.example.com"})
+	h := request("https://a.example.com")
+	assert.Equal(t, "https://a.example.com", h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", h.Get("Vary"))
+
+	// The denied responses vary on the origin too, or a cache serves them to the allowed one.
+	h = request("https://b.example.com")
+	assert.Empty(t, h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", h.Get("Vary"))
+
+	SetCORSOrigins([]string{"*"})
+	h = request("https://b.example.com")
+	assert.Equal(t, "*", h.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, h.Get("Vary"))
+}
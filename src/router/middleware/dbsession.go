@@ -0,0 +1,13 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/service"
+)
+
+// DBSession is a middleware function that starts a database session for the request,
+// the reads after a write in the request go to the primary instead of the replicas.
+func DBSession(c *gin.Context) {
+	c.Request = c.Request.WithContext(service.WithSession(c.Request.Context()))
+	c.Next()
+}
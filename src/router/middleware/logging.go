@@ -1 +1,192 @@
 package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lexkong/log"
+	"github.com/lexkong/log/lager"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/util"
+)
+
+// bodyWriter keeps the first bytes of the response body.
+type bodyWriter struct {
+	gin.ResponseWriter
+	body  bytes.Buffer
+	limit int
+}
+
+func (w *bodyWriter) Write(b []byte) (int, error) {
+	if n := w.limit - w.body.Len(); n > 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		w.body.Write(b[:n])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Logging writes a structured access log for each request, the paths of `core.access_log.skip_paths` are skipped.
+// In debug mode, the request and the response bodies are logged with `core.access_log.body`.
+func Logging() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := config.Current()
+		if conf == nil {
+			c.Next()
+			return
+		}
+		accessLog(c, conf.Core.AccessLog)
+	}
+}
+
+// accessLog runs the handlers, and writes the log of the request with the settings.
+func accessLog(c *gin.Context, access config.SectionAccessLog) {
+	if !access.Enabled || skipped(c.Request.URL.Path, access.SkipPaths) {
+		c.Next()
+		return
+	}
+
+	start := time.Now()
+
+	var reqBody []byte
+	var w *bodyWriter
+	capture := access.Body && gin.IsDebugging()
+	if capture {
+		reqBody = peekBody(c.Request, access.MaxBodySize)
+		w = &bodyWriter{ResponseWriter: c.Writer, limit: access.MaxBodySize}
+		c.Writer = w
+	}
+
+	c.Next()
+
+	status := c.Writer.Status()
+	size := c.Writer.Size()
+	if size < 0 {
+		size = 0
+	}
+
+	data := lager.Data{
+		"method":     c.Request.Method,
+		"route":      c.FullPath(),
+		"path":       c.Request.URL.Path,
+		"status":     status,
+		"latency":    time.Since(start).String(),
+		"bytes":      size,
+		"ip":         c.ClientIP(),
+		"user_id":    util.GetUserID(c),
+		"request_id": util.GetReqID(c),
+	}
+	if len(c.Errors) > 0 {
+		data["errors"] = c.Errors.String()
+	}
+	if capture {
+		data["request_body"] = maskBody(reqBody, c.ContentType(), access.MaxBodySize, access.MaskFields)
+		data["response_body"] = maskBody(w.body.Bytes(), w.Header().Get("Content-Type"), access.MaxBodySize, access.MaskFields)
+	}
+
+	writeLog(status, data)
+}
+
+// writeLog writes the access log at the level of the status, 测试时替换
+var writeLog = func(status int, data lager.Data) {
+	switch {
+	case status >= http.StatusInternalServerError:
+		log.Error("HTTP request", errors.New(http.StatusText(status)), data)
+	case status >= http.StatusBadRequest:
+		log.Warn("HTTP request", data)
+	default:
+		log.Info("HTTP request", data)
+	}
+}
+
+func skipped(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// peekBody returns up to limit+1 bytes of the request body, the handlers still read the whole body.
+func peekBody(r *http.Request, limit int) []byte {
+	if r.Body == nil {
+		return nil
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+	if err != nil {
+		log.Error("Read the request body failed.", err)
+	}
+	r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(b), r.Body))
+	return b
+}
+
+// maskBody returns the JSON or form body with the values of the mask fields replaced,
+// the other bodies and the truncated ones are logged by size only, as they can't be masked.
+func maskBody(b []byte, contentType string, limit int, fields []string) string {
+	if len(b) == 0 {
+		return ""
+	}
+	if len(b) > limit {
+		return fmt.Sprintf("[truncated, more than %d bytes]", limit)
+	}
+
+	switch {
+	case strings.Contains(contentType, "json"):
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err == nil {
+			masked, _ := json.Marshal(maskValue(v, fields))
+			return string(masked)
+		}
+	case strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		if values, err := url.ParseQuery(string(b)); err == nil {
+			for k := range values {
+				if masked(k, fields) {
+					values[k] = []string{"******"}
+				}
+			}
+			return values.Encode()
+		}
+	}
+	return fmt.Sprintf("[%d bytes]", len(b))
+}
+
+func maskValue(v interface{}, fields []string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if masked(k, fields) {
+				v[k] = "******"
+			} else {
+				v[k] = maskValue(value, fields)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = maskValue(v[i], fields)
+		}
+	}
+	return v
+}
+
+// masked reports whether the name of the field contains any of the fields.
+func masked(name string, fields []string) bool {
+	name = strings.ToLower(name)
+	for _, f := range fields {
+		if f != "" && strings.Contains(name, strings.ToLower(f)) {
+			return true
+		}
+	}
+	return false
+}
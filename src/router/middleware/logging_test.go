@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lexkong/log/lager"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskBody(t *testing.T) {
+	fields := []string{"password", "token"}
+
+	body := maskBody([]byte(`{"username":"admin","password":"s3cret","data":{"refresh_token":"abc"}}`), "application/json; charset=utf-8", 4096, fields)
+	assert.NotContains(t, body, "s3cret")
+	assert.NotContains(t, body, "abc")
+	assert.Contains(t, body, `"username":"admin"`)
+
+	body = maskBody([]byte("username=admin&Password=s3cret"), "application/x-www-form-urlencoded", 4096, fields)
+	assert.Equal(t, "Password=%2A%2A%2A%2A%2A%2A&username=admin", body)
+
+	// The bodies which can't be masked are logged by size only.
+	assert.Equal(t, "[11 bytes]", maskBody([]byte("password=1&"), "text/plain", 4096, fields))
+	assert.Equal(t, "[truncated, more than 8 bytes]", maskBody([]byte(`{"password":"s3cret"}`), "application/json", 8, fields))
+}
+
+func TestAccessLog(t *testing.T) {
+	var records []lager.Data
+	defer func(fn func(int, lager.Data)) { writeLog = fn }(writeLog)
+	writeLog = func(status int, data lager.Data) {
+		records = append(records, data)
+	}
+
+	gin.SetMode(gin.TestMode)
+	access := config.SectionAccessLog{Enabled: true, SkipPaths: []string{"/sd/"}}
+	g := gin.New()
+	g.Use(RequestId(), func(c *gin.Context) { accessLog(c, access) })
+	g.GET("/v1/user/:username", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	g.GET("/sd/health", func(c *gin.Context) { c.String(http.StatusOK, "OK") })
+
+	req, _ := http.NewRequest("GET", "/v1/user/admin", nil)
+	req.Header.Set("X-Request-Id", "f2b1c0de")
+	g.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Len(t, records, 1)
+	assert.Equal(t, "f2b1c0de", records[0]["request_id"])
+	assert.Equal(t, "/v1/user/:username", records[0]["route"])
+	assert.Equal(t, http.StatusOK, records[0]["status"])
+
+	// The skipped paths aren't logged.
+	req, _ = http.NewRequest("GET", "/sd/health", nil)
+	g.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Len(t, records, 1)
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/moocss/apiserver/src/util"
+)
+
+// Rule grants the access to the request without the permission, e.g. self-service.
+type Rule func(c *gin.Context) bool
+
+// OwnerID allows the user to access the resource identified by its own id in the path parameter.
+func OwnerID(param string) Rule {
+	return func(c *gin.Context) bool {
+		id, err := strconv.ParseUint(c.Param(param), 10, 64)
+		return err == nil && id == util.GetUserID(c)
+	}
+}
+
+// OwnerName allows the user to access the resource identified by its own username in the path parameter.
+func OwnerName(param string) Rule {
+	return func(c *gin.Context) bool {
+		username := c.Param(param)
+		return username != "" && username == util.GetUsername(c)
+	}
+}
+
+// Permission requires the authenticated user to have the permission,
+// unless one of the rules grants the access. It must be used after AuthMiddleware.
+func Permission(permission string, rules ...Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, rule := range rules {
+			if rule(c) {
+				c.Next()
+				return
+			}
+		}
+
+		ok, err := service.Role.HasPermission(util.GetUserID(c), permission)
+		if err != nil {
+			util.SendResponse(c, errno.ErrDatabase, nil)
+			c.Abort()
+			return
+		}
+		if !ok {
+			util.SendResponse(c, errno.ErrPermissionDenied, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/util"
+	"golang.org/x/time/rate"
+)
+
+// limiter holds the global *rate.Limiter, nil if the rate limit is disabled.
+var limiter atomic.Value
+
+// SetRateLimit changes the global rate limit, rps requests per second with the burst.
+func SetRateLimit(enabled bool, rps float64, burst int) {
+	if !enabled {
+		limiter.Store((*rate.Limiter)(nil))
+		return
+	}
+	limiter.Store(rate.NewLimiter(rate.Limit(rps), burst))
+}
+
+// RateLimit is a middleware function that rejects the requests over the global rate limit,
+// the health checks are never limited.
+func RateLimit(c *gin.Context) {
+	l, _ := limiter.Load().(*rate.Limiter)
+	if l != nil && !strings.HasPrefix(c.Request.URL.Path, "/sd/") && !l.Allow() {
+		util.SendResponse(c, errno.ErrTooManyRequests, nil)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
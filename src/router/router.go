@@ -0,0 +1,107 @@
+package router
+
+import (
+	"github.com/moocss/apiserver/src/api/catalog"
+	"github.com/moocss/apiserver/src/api/role"
+	"github.com/moocss/apiserver/src/api/sd"
+	"github.com/moocss/apiserver/src/pkg/constvar"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/pkg/version"
+	"github.com/moocss/apiserver/src/router/middleware"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/moocss/apiserver/src/util"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/api/user"
+)
+
+func rootHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"text": "Welcome to api server.",
+	})
+}
+
+func versionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"source":  "https://github.com/go-impatient/apiserver",
+		"version": version.GetVersion(),
+	})
+}
+
+// Load loads the middlewares, routes, handlers.
+func Load(g *gin.Engine, mw ...gin.HandlerFunc) *gin.Engine {
+	// Middlewares.
+	g.Use(middleware.RequestId())
+	g.Use(middleware.Logging())
+	g.Use(gin.Recovery())
+	g.Use(middleware.NoCache)
+	g.Use(middleware.Options)
+	g.Use(middleware.Secure)
+	g.Use(middleware.RateLimit)
+	g.Use(middleware.DBSession)
+	g.Use(mw...)
+
+	g.GET("/version", versionHandler)
+	g.GET("/", rootHandler)
+
+	// 404 Handler.
+	g.NoRoute(func(c *gin.Context) {
+		util.SendResponse(c, errno.ErrRouteNotFound, nil)
+	})
+
+	uh := user.NewHandler(service.User)
+
+	// 用户登录
+	g.POST("/v1/login", uh.Login)
+	g.POST("/v1/token/refresh", uh.Refresh)
+
+	// The error catalog
+	g.GET("/v1/errors", catalog.List)
+
+	// 会话管理
+	auth := g.Group("/v1")
+	auth.Use(middleware.AuthMiddleware())
+	{
+		auth.POST("/logout", uh.Logout)
+		auth.GET("/sessions", uh.ListSessions)
+		auth.DELETE("/sessions/:id", uh.DeleteSession)
+	}
+
+	// User API
+	u := g.Group("/v1/user")
+	u.Use(middleware.AuthMiddleware())
+	{
+		// u.POST("", user.Create)
+		u.POST("", middleware.Permission(constvar.PermUsersCreate), uh.Create)
+		u.GET("", middleware.Permission(constvar.PermUsersList), uh.List)
+		u.GET("/:username", middleware.Permission(constvar.PermUsersGet, middleware.OwnerName("username")), uh.Get)
+		u.PUT("/:id", middleware.Permission(constvar.PermUsersUpdate, middleware.OwnerID("id")), uh.Update)
+		u.DELETE("/:id", middleware.Permission(constvar.PermUsersDelete), uh.Delete)
+	}
+
+	// Role API
+	admin := g.Group("/v1/admin")
+	admin.Use(middleware.AuthMiddleware())
+	{
+		admin.GET("/roles", middleware.Permission(constvar.PermRolesList), role.List)
+		admin.POST("/roles", middleware.Permission(constvar.PermRolesManage), role.Create)
+		admin.GET("/users/:id/roles", middleware.Permission(constvar.PermRolesList), role.GetUserRoles)
+		admin.POST("/users/:id/roles", middleware.Permission(constvar.PermRolesManage), role.Assign)
+		admin.DELETE("/users/:id/roles/:role", middleware.Permission(constvar.PermRolesManage), role.Revoke)
+	}
+
+	// The health check handlers
+	svcd := g.Group("/sd")
+	{
+		svcd.GET("/health", sd.HealthCheck)
+		svcd.GET("/live", sd.Live)
+		svcd.GET("/ready", sd.Ready)
+		svcd.GET("/disk", sd.DiskCheck)
+		svcd.GET("/cpu", sd.CPUCheck)
+		svcd.GET("/ram", sd.RAMCheck)
+		svcd.GET("/tls", sd.TLSCheck)
+	}
+
+	return g
+}
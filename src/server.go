@@ -1,19 +1,24 @@
 package src
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
-	"github.com/moocss/apiserver/src/service"
+	"github.com/moocss/apiserver/src/api/sd"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/pkg/cert"
+	"github.com/moocss/apiserver/src/pkg/shutdown"
 	"github.com/moocss/apiserver/src/router"
 	"github.com/moocss/apiserver/src/router/middleware"
 	"golang.org/x/crypto/acme/autocert"
 	"github.com/lexkong/log"
+	"golang.org/x/sync/errgroup"
+	"net"
 	"net/http"
 	"crypto/tls"
+	"strings"
+	"sync/atomic"
 	"time"
 	"errors"
-	"os"
-	"os/signal"
-	"syscall"
 )
 
 // New returns a app instance
@@ -24,99 +29,258 @@ func New() *gin.Engine {
 	// Create the Gin engine.
 	g := gin.New()
 
+	// Middlwares
+	mw := []gin.HandlerFunc{
+		middleware.VersionMiddleware(),
+	}
+	if Conf.Core.HSTS.Enabled {
+		mw = append(mw, middleware.HSTS(Conf.Core.HSTS.MaxAge, Conf.Core.HSTS.IncludeSubdomains, Conf.Core.HSTS.Preload))
+	}
+
 	// Routes
 	router.Load(
 		// Cores
 		g,
-		// Middlwares
-		middleware.VersionMiddleware(),
-		// middleware.Logging(),
-		middleware.RequestId(),
+		mw...,
 	)
 	return g
 }
 
-func autoTLSServer() *http.Server {
-	m := autocert.Manager{
-		Prompt:     autocert.AcceptTOS,
-		HostPolicy: autocert.HostWhitelist(Conf.Core.AutoTLS.Host),
-		Cache:      autocert.DirCache(Conf.Core.AutoTLS.Folder),
-	}
+func autoTLSServer(m *autocert.Manager, handler http.Handler) *http.Server {
 	return &http.Server{
-		Addr:      	":https",
+		Addr:      	Conf.Core.Address + ":https",
 		TLSConfig: 	&tls.Config{GetCertificate: m.GetCertificate},
-		Handler:  	New(),
+		Handler:  	handler,
 	}
 }
 
-func defaultTLSServer() *http.Server {
+func defaultTLSServer(tlsConfig *tls.Config, handler http.Handler) *http.Server {
 	return &http.Server{
-		Addr: 			"0.0.0.0:" + Conf.Core.TLS.Port,
-		Handler:	  New(),
+		Addr: 			Conf.Core.Address + ":" + Conf.Core.TLS.Port,
+		TLSConfig: 	tlsConfig,
+		Handler:	  handler,
 	}
 }
 
-func defaultServer() *http.Server {
+// defaultTLSConfig serves the reloadable certificate, and verifies the client certificates in mutual TLS mode.
+func defaultTLSConfig(loader *cert.Loader, conf config.SectionTLS) (*tls.Config, error) {
+	clientAuth, err := cert.ClientAuthType(conf.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		GetCertificate: loader.GetCertificate,
+		ClientAuth:     clientAuth,
+		// GetConfigForClient 返回的配置不继承外层的 NextProtos, 否则不协商 HTTP/2
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if clientAuth != tls.NoClientCert {
+		if tlsConfig.ClientCAs, err = cert.LoadClientCAs(conf.ClientCA); err != nil {
+			return nil, err
+		}
+	}
+	return tlsConfig, nil
+}
+
+// liveTLSConfig returns the tls.Config of the server, the handshakes use the latest config stored in live.
+func liveTLSConfig(loader *cert.Loader, live *atomic.Value) *tls.Config {
+	return &tls.Config{
+		GetCertificate: loader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return live.Load().(*tls.Config), nil
+		},
+	}
+}
+
+// reloadTLS applies the changed key pair and client authentication settings,
+// the current ones are kept if the new settings are invalid. plainOpen reports
+// whether the plain http listener serves the routes instead of redirecting.
+func reloadTLS(loader *cert.Loader, live *atomic.Value, old, conf config.SectionTLS, plainOpen bool) {
+	if conf.CertPath == "" || conf.KeyPath == "" {
+		// Disabling https is applied after a restart.
+		return
+	}
+
+	if old.CertPath != conf.CertPath || old.KeyPath != conf.KeyPath {
+		if err := loader.SetPaths(conf.CertPath, conf.KeyPath); err != nil {
+			log.Errorf(err, "Load the TLS certificate %s failed, keep serving the previous one.", conf.CertPath)
+		}
+	}
+
+	if old.ClientAuth != conf.ClientAuth || old.ClientCA != conf.ClientCA {
+		// 明文端口在启动时决定, 开放时 require 可以被绕过
+		if conf.ClientAuth == "require" && plainOpen {
+			log.Errorf(errors.New("the plain http listener isn't redirecting"), "Apply the TLS client authentication require failed, restart with core.tls.redirect, keep the previous one.")
+			return
+		}
+		tlsConfig, err := defaultTLSConfig(loader, conf)
+		if err != nil {
+			log.Errorf(err, "Apply the TLS client authentication failed, keep the previous one.")
+			return
+		}
+		live.Store(tlsConfig)
+		log.Infof("TLS client authentication changed to %s.", conf.ClientAuth)
+	}
+}
+
+func defaultServer(handler http.Handler) *http.Server {
 	return &http.Server{
-		Addr: 			"0.0.0.0:" + Conf.Core.Port,
-		Handler:	  New(),
+		Addr: 			Conf.Core.Address + ":" + Conf.Core.Port,
+		Handler:	  handler,
+	}
+}
+
+// httpsPort returns the port of the live https listener, or empty if https is disabled.
+func httpsPort() string {
+	if Conf.Core.AutoTLS.Enabled {
+		return "443"
+	}
+	if Conf.Core.TLS.CertPath != "" && Conf.Core.TLS.KeyPath != "" {
+		return Conf.Core.TLS.Port
 	}
+	return ""
 }
 
-// RunHTTPServer provide run http or https protocol.
+// redirectHTTPS redirects the plain http requests to https,
+// except the health checks and the ACME challenges.
+func redirectHTTPS(next http.Handler, port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/sd/") || strings.HasPrefix(r.URL.Path, "/.well-known/acme-challenge/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		// 308 keeps the method and the body of the other requests.
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	})
+}
+
+// RunHTTPServer provide run http and https protocol, the plain http listener
+// runs along with the https one unless `core.port` is empty.
 func RunHTTPServer() (err error) {
 	if !Conf.Core.Enabled {
 		log.Debug("httpd server is disabled.")
 		return nil
 	}
 
+	var (
+		g       errgroup.Group
+		handler = New()
+		plain   http.Handler = handler
+	)
+
+	if port := httpsPort(); port != "" && Conf.Core.TLS.Redirect {
+		plain = redirectHTTPS(handler, port)
+	}
+	plainOpen := Conf.Core.Port != "" && !Conf.Core.TLS.Redirect
+
 	if Conf.Core.AutoTLS.Enabled {
-		s := autoTLSServer()
-		handleSignal(s)
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(Conf.Core.AutoTLS.Host),
+			Cache:      autocert.DirCache(Conf.Core.AutoTLS.Folder),
+		}
+		// The plain http listener answers the ACME http-01 challenges.
+		plain = m.HTTPHandler(plain)
+
+		s := autoTLSServer(m, handler)
 		log.Infof("1. Start to listening the incoming requests on https address")
-		err = s.ListenAndServeTLS("", "")
+		serve(&g, s, func() error { return s.ListenAndServeTLS("", "") })
 	} else if Conf.Core.TLS.CertPath != "" && Conf.Core.TLS.KeyPath != "" {
-		s := defaultTLSServer()
-		handleSignal(s)
+		// The certificate is reloaded when the files are renewed.
+		loader, err := cert.NewLoader(Conf.Core.TLS.CertPath, Conf.Core.TLS.KeyPath)
+		if err != nil {
+			return err
+		}
+		// The client CA is checked before the watcher starts.
+		tlsConfig, err := defaultTLSConfig(loader, Conf.Core.TLS)
+		if err != nil {
+			return err
+		}
+
+		if err := loader.Watch(); err != nil {
+			return err
+		}
+		shutdown.Register(shutdown.PhaseWorker, "tls certificate watcher", func(ctx context.Context) error {
+			return loader.Close()
+		})
+		sd.SetCertificate(loader)
+
+		// The certificate and the client authentication can change with the config file.
+		var live atomic.Value
+		live.Store(tlsConfig)
+		config.Subscribe(func(old, conf *config.ConfYaml) {
+			reloadTLS(loader, &live, old.Core.TLS, conf.Core.TLS, plainOpen)
+		})
+
+		s := defaultTLSServer(liveTLSConfig(loader, &live), handler)
 		log.Infof("2. Start to listening the incoming requests on https address: %s", Conf.Core.TLS.Port)
-		err = s.ListenAndServeTLS(Conf.Core.TLS.CertPath, Conf.Core.TLS.KeyPath)
-	} else {
-		s := defaultServer()
-		handleSignal(s)
+		serve(&g, s, func() error { return s.ListenAndServeTLS("", "") })
+	}
+
+	if Conf.Core.Port != "" {
+		s := defaultServer(plain)
 		log.Infof("3. Start to listening the incoming requests on http address: %s", Conf.Core.Port)
-		err = s.ListenAndServe()
+		serve(&g, s, s.ListenAndServe)
 	}
 
-	return
+	return g.Wait()
 }
 
-// handleSignal handles system signal for graceful shutdown.
-func handleSignal(server *http.Server) {
-	c := make(chan os.Signal)
-	signal.Notify(c, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+// serve runs the server in the group, a listener failure shuts down the others.
+func serve(g *errgroup.Group, s *http.Server, listen func() error) {
+	shutdown.Register(shutdown.PhaseHTTP, "http server "+s.Addr, gracefulShutdown(s))
 
-	go func() {
-		s := <-c
-		log.Infof("got signal [%s], exiting apiserver now", s)
-		if err := server.Close(); nil != err {
-			log.Errorf(err, "server close failed ")
+	g.Go(func() error {
+		// Shutdown makes the server return immediately, the in-flight requests are drained by the hook.
+		err := listen()
+		if err == http.ErrServerClosed {
+			return nil
 		}
 
-		service.DB.Close()
+		go shutdown.Shutdown(Conf.Core.ShutdownTimeout, 0)
+		return err
+	})
+}
 
-		log.Infof("apiserver exited")
-		os.Exit(0)
-	}()
+// gracefulShutdown waits for the in-flight requests, and closes the remaining connections on timeout.
+func gracefulShutdown(s *http.Server) shutdown.Hook {
+	return func(ctx context.Context) error {
+		if err := s.Shutdown(ctx); err != nil {
+			s.Close()
+			return err
+		}
+		return nil
+	}
 }
 
 // PingServer
 func PingServer() (err error) {
+	url, client := pingTarget()
+
 	maxPingConf := Conf.Core.MaxPingCount
 	for i := 0; i < maxPingConf; i++ {
-		// Ping the server by sending a GET request to `/health`.
-		resp, err := http.Get("http://localhost:" + Conf.Core.Port + "/sd/health")
-		if err == nil && resp.StatusCode == 200 {
-			return nil
+		// Ping the server by sending a GET request to `/sd/live`.
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == 200 {
+				return nil
+			}
 		}
 
 		// Sleep for a second to continue the next ping.
@@ -126,3 +290,19 @@ func PingServer() (err error) {
 	err = errors.New("Cannot connect to the router.")
 	return err
 }
+
+// pingTarget returns the health check url of the live listener, prefers the plain http one.
+func pingTarget() (string, *http.Client) {
+	url := Conf.LocalURL("/sd/live")
+	if strings.HasPrefix(url, "http://") {
+		return url, http.DefaultClient
+	}
+
+	// The certificate is issued for the public host, not localhost.
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	return url, client
+}
@@ -1,95 +1,290 @@
 package service
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
 	"fmt"
-	// MySQL driver.
+	"io/ioutil"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
+	// MySQL driver.
 	_ "github.com/jinzhu/gorm/dialects/mysql"
+	// SQLite driver.
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
 
 	"github.com/lexkong/log"
-	"github.com/spf13/viper"
+	"github.com/lexkong/log/lager"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/model"
+	"github.com/moocss/apiserver/src/pkg/constvar"
 )
 
-
-
 type Database struct {
-	Self 		*gorm.DB
-	// Docker 	*gorm.DB
+	// Self is the primary database.
+	Self     *gorm.DB
+	Replicas *ReplicaPool
 }
 
 var DB *Database
 
-func realDSN(dbname, username, password, addr string) string {
-	conf := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8&parseTime=%t&loc=%s",
-		username,
-		password,
-		addr,
-		dbname,
-		true,
-		"Local")
-	return conf
+const (
+	// mysqlTLSConfig is the name of the TLS config registered for `db.tls_ca`.
+	mysqlTLSConfig = "apiserver"
+
+	// 启动时连接数据库失败的重试间隔, 按指数增长
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 10 * time.Second
+)
+
+// realDSN returns the MySQL DSN with the charset, timezone, TLS and timeouts of the config.
+func realDSN(conf *config.SectionDb, dbname, username, password, addr string) (string, error) {
+	loc, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		return "", err
+	}
+
+	c := mysql.NewConfig()
+	c.User = username
+	c.Passwd = password
+	c.Net = "tcp"
+	c.Addr = addr
+	c.DBName = dbname
+	c.ParseTime = true
+	c.Loc = loc
+	c.Params = map[string]string{"charset": conf.Charset}
+	c.Timeout = conf.Timeout
+	c.ReadTimeout = conf.ReadTimeout
+	c.WriteTimeout = conf.WriteTimeout
+
+	if c.TLSConfig, err = mysqlTLS(conf); err != nil {
+		return "", err
+	}
+
+	return c.FormatDSN(), nil
 }
 
-func openDB(connStr string) *gorm.DB {
-	db, err := gorm.Open("mysql", connStr)
+// mysqlTLS returns the tls parameter of the DSN, the server certificate is verified with `db.tls_ca` if it's set.
+func mysqlTLS(conf *config.SectionDb) (string, error) {
+	if conf.TLSCA == "" {
+		return conf.TLS, nil
+	}
+
+	pem, err := ioutil.ReadFile(conf.TLSCA)
 	if err != nil {
-		log.Errorf(err, "Database connection failed.")
-	} else {
-		log.Infof("Database connection succeed.")
+		return "", err
 	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return "", errors.New("no certificate found in " + conf.TLSCA)
+	}
+
+	if err := mysql.RegisterTLSConfig(mysqlTLSConfig, &tls.Config{RootCAs: pool}); err != nil {
+		return "", err
+	}
+	return mysqlTLSConfig, nil
+}
+
+// openDB connects to the database, it retries with exponential backoff until `db.connect_max_wait`,
+// e.g. while the database container is starting.
+func openDB(conf *config.SectionDb, dialect, connStr string) (*gorm.DB, error) {
+	deadline := time.Now().Add(conf.ConnectMaxWait)
+	backoff := minBackoff
+
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(dialect, connStr)
+		if err == nil {
+			log.Infof("Database connection succeed.")
+
+			// set for db connection
+			db.LogMode(conf.LogMode)
+			// 禁止没有条件的 update 和 delete
+			db.BlockGlobalUpdate(true)
+			setPool(db.DB(), conf)
+
+			// SQLite doesn't support concurrent writers.
+			if dialect == "sqlite3" {
+				db.DB().SetMaxOpenConns(1)
+			}
+			return db, nil
+		}
 
-	// set for db connection
-	setupDB(db)
+		if time.Now().Add(backoff).After(deadline) {
+			return nil, fmt.Errorf("database connection failed after %d attempts: %v", attempt, err)
+		}
+		log.Warnf("Database connection failed, retry in %s: %v", backoff, err)
 
-	return db
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
 }
 
-func setupDB(db *gorm.DB) {
-	db.LogMode(true)
-	db.DB().SetMaxOpenConns(50) // 用于设置最大打开的连接数，默认值为0表示不限制.设置最大的连接数，可以避免并发太高导致连接mysql出现too many connections的错误。
-	db.DB().SetMaxIdleConns(10) // 用于设置闲置的连接数.设置闲置的连接数则当开启的一个连接使用完成后可以放在池里等候下一次使用。
+// setPool sets the pool sizes, the setters of sql.DB are safe to call on the live pool.
+func setPool(db *sql.DB, conf *config.SectionDb) {
+	db.SetMaxOpenConns(conf.MaxOpenConns)       // 用于设置最大打开的连接数，默认值为0表示不限制.设置最大的连接数，可以避免并发太高导致连接mysql出现too many connections的错误。
+	db.SetMaxIdleConns(conf.MaxIdleConns)       // 用于设置闲置的连接数.设置闲置的连接数则当开启的一个连接使用完成后可以放在池里等候下一次使用。
+	db.SetConnMaxLifetime(conf.ConnMaxLifetime) // 连接的最长使用时间, 避免使用被 MySQL wait_timeout 或代理关闭的连接
+	db.SetConnMaxIdleTime(conf.ConnMaxIdleTime) // 连接的最长闲置时间
 }
 
 // Init client storage.
-func InitSelfDB() *gorm.DB {
-	return openDB(realDSN(
-		viper.GetString("db.name"),
-		viper.GetString("db.username"),
-		viper.GetString("db.password"),
-		viper.GetString("db.addr"),
-	))
+func InitSelfDB() (*gorm.DB, error) {
+	c := config.Current()
+	if c == nil {
+		return nil, errors.New("the config isn't loaded")
+	}
+	conf := &c.Db
+
+	if conf.Driver == "sqlite3" {
+		db, err := openDB(conf, "sqlite3", conf.Path)
+		if err != nil {
+			return nil, err
+		}
+		// SQLite is used for development and tests, create the tables on the fly.
+		db.AutoMigrate(
+			&model.UserModel{},
+			&model.RefreshTokenModel{},
+			&model.RoleModel{},
+			&model.RolePermissionModel{},
+			&model.UserRoleModel{},
+		)
+		if err := seedRoles(db); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return db, nil
+	}
+
+	dsn, err := realDSN(conf, conf.Name, conf.Username, conf.Password, conf.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return openDB(conf, "mysql", dsn)
 }
 
-func GetSelfDB() *gorm.DB {
+// seedRoles creates the default roles of the migration 20200317000003 in an empty SQLite database,
+// the admin is created by `apiserver user create <username> --role admin`.
+func seedRoles(db *gorm.DB) error {
+	var count int
+	if err := db.Model(&model.RoleModel{}).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+
+	tx := db.Begin()
+	roles := []*model.RoleModel{
+		{BaseModel: model.BaseModel{ID: 1}, Name: "admin", Description: "Administrator",
+			Permissions: []model.RolePermissionModel{{Permission: constvar.PermAll}}},
+		{BaseModel: model.BaseModel{ID: 2}, Name: "user", Description: "Regular user",
+			Permissions: []model.RolePermissionModel{{Permission: constvar.PermUsersGet}}},
+	}
+	for _, r := range roles {
+		if err := tx.Create(r).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+func GetSelfDB() (*gorm.DB, error) {
 	return InitSelfDB()
 }
 
-//func InitDockerDB() *gorm.DB {
-//	return openDB(realDSN(
-//		viper.GetString("docker_db.name"),
-//		viper.GetString("docker_db.username"),
-//		viper.GetString("docker_db.password"),
-//		viper.GetString("docker_db.addr"),
-//	))
-//}
-//
-//func GetDockerDB() *gorm.DB {
-//	return InitDockerDB()
-//}
-
-func (db *Database) Init() {
+// openReplicas opens the read replicas of the primary, an unreachable replica is used after it's healthy.
+func openReplicas(primary *gorm.DB) (*ReplicaPool, error) {
+	pool := NewReplicaPool(primary)
+
+	c := config.Current()
+	if c == nil || c.Db.Driver == "sqlite3" {
+		return pool, nil
+	}
+	conf := &c.Db
+
+	for _, r := range conf.Replicas {
+		name, username, password := r.Name, r.Username, r.Password
+		if name == "" {
+			name = conf.Name
+		}
+		if username == "" {
+			username = conf.Username
+		}
+		if password == "" {
+			password = conf.Password
+		}
+
+		dsn, err := realDSN(conf, name, username, password, r.Addr)
+		if err != nil {
+			return nil, err
+		}
+
+		// Open the pool without closing it on the failed ping, the health checks reconnect it.
+		sqlDB, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return nil, err
+		}
+		db, err := gorm.Open("mysql", sqlDB)
+		if err != nil {
+			log.Error("Database replica connection failed, it's used after it's healthy.", err, lager.Data{"addr": r.Addr})
+		} else {
+			log.Info("Database replica connection succeed.", lager.Data{"addr": r.Addr})
+		}
+		db.LogMode(conf.LogMode)
+		setPool(sqlDB, conf)
+
+		pool.Add(r.Addr, db, err == nil)
+	}
+
+	pool.Watch(conf.ReplicaCheckInterval)
+	return pool, nil
+}
+
+// Init connects to the primary and the replicas, it fails if the primary never comes up.
+func (db *Database) Init() error {
+	self, err := GetSelfDB()
+	if err != nil {
+		return err
+	}
+
+	replicas, err := openReplicas(self)
+	if err != nil {
+		self.Close()
+		return err
+	}
+
 	DB = &Database{
-		Self:  GetSelfDB(),
-		// Docker: GetDockerDB(),
+		Self:     self,
+		Replicas: replicas,
+	}
+
+	User = NewUserService(NewGormUserRepository(DB.Replicas))
+
+	registerHealthChecks(self)
+	return nil
+}
+
+// Configure applies the pool sizes to the primary and the replicas, SQLite keeps a single connection.
+// The log mode isn't changed on the live connections, it needs a restart.
+func (db *Database) Configure(conf *config.SectionDb) {
+	if db == nil || db.Self == nil || db.Self.Dialect().GetName() == "sqlite3" {
+		return
+	}
+
+	setPool(db.Self.DB(), conf)
+	for _, r := range db.Replicas.replicas {
+		setPool(r.db.DB(), conf)
 	}
 }
 
 func (db *Database) Close() {
+	unregisterHealthChecks()
+	DB.Replicas.Close()
+
 	if err := DB.Self.Close(); nil != err {
 		log.Error("Disconnect from database failed: ", err)
 	}
-
-	//if err := DB.Docker.Close(); nil != err {
-	//	log.Error("Disconnect from database failed: ", err)
-	//}
-}
\ No newline at end of file
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealDSN(t *testing.T) {
+	conf := &config.SectionDb{
+		Charset:     "utf8mb4",
+		Timezone:    "Asia/Shanghai",
+		TLS:         "skip-verify",
+		Timeout:     5 * time.Second,
+		ReadTimeout: 10 * time.Second,
+	}
+
+	dsn, err := realDSN(conf, "db_apiserver", "root", "p@ss:word/", "127.0.0.1:3306")
+	assert.NoError(t, err)
+	c, err := mysql.ParseDSN(dsn)
+	assert.NoError(t, err)
+	assert.Equal(t, "root", c.User)
+	assert.Equal(t, "p@ss:word/", c.Passwd)
+	assert.Equal(t, "127.0.0.1:3306", c.Addr)
+	assert.Equal(t, "db_apiserver", c.DBName)
+	assert.Equal(t, "utf8mb4", c.Params["charset"])
+	assert.Equal(t, "Asia/Shanghai", c.Loc.String())
+	assert.Equal(t, "skip-verify", c.TLSConfig)
+	assert.True(t, c.ParseTime)
+	assert.Equal(t, 5*time.Second, c.Timeout)
+	assert.Equal(t, 10*time.Second, c.ReadTimeout)
+
+	// The server certificate is verified with the CA.
+	conf.TLS = ""
+	conf.TLSCA = "../config/server.crt"
+	dsn, err = realDSN(conf, "db_apiserver", "root", "", "127.0.0.1:3306")
+	assert.NoError(t, err)
+	c, err = mysql.ParseDSN(dsn)
+	assert.NoError(t, err)
+	assert.Equal(t, mysqlTLSConfig, c.TLSConfig)
+
+	conf.TLSCA = "missing.crt"
+	_, err = realDSN(conf, "db_apiserver", "root", "", "127.0.0.1:3306")
+	assert.Error(t, err)
+
+	conf.TLSCA = ""
+	conf.Timezone = "Mars/Olympus"
+	_, err = realDSN(conf, "db_apiserver", "root", "", "127.0.0.1:3306")
+	assert.Error(t, err)
+}
+
+func TestOpenDBRetry(t *testing.T) {
+	conf := &config.SectionDb{ConnectMaxWait: time.Second}
+
+	db, err := openDB(conf, "sqlite3", ":memory:")
+	assert.NoError(t, err)
+	db.Close()
+
+	// Nothing listens on the port: the retry after 500ms fails, the next one would pass the deadline.
+	start := time.Now()
+	_, err = openDB(conf, "mysql", "root@tcp(127.0.0.1:1)/db_apiserver?timeout=100ms")
+	elapsed := time.Since(start)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "after 2 attempts")
+	assert.True(t, elapsed >= minBackoff && elapsed < conf.ConnectMaxWait, elapsed.String())
+
+	// No retry without wait.
+	conf.ConnectMaxWait = 0
+	_, err = openDB(conf, "mysql", "root@tcp(127.0.0.1:1)/db_apiserver?timeout=100ms")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "after 1 attempts")
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lexkong/log"
+	"github.com/moocss/apiserver/src/migration"
+	"github.com/moocss/apiserver/src/pkg/health"
+)
+
+// registerHealthChecks adds the readiness checks of the primary database.
+func registerHealthChecks(db *gorm.DB) {
+	health.Register("database", health.CheckerFunc(func(ctx context.Context) error {
+		if err := db.DB().PingContext(ctx); err != nil {
+			return publicError("database", err, "database is unreachable")
+		}
+		return nil
+	}))
+
+	// SQLite creates the tables on the fly, the migrations are for MySQL only.
+	if db.Dialect().GetName() != "mysql" {
+		return
+	}
+	health.Register("migrations", health.CheckerFunc(func(ctx context.Context) error {
+		pending, err := migration.New(db).Pending(ctx)
+		if err != nil {
+			return publicError("migrations", err, "migration status is unavailable")
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d migrations pending, the first is %d", len(pending), pending[0].Version)
+		}
+		return nil
+	}))
+}
+
+// publicError logs the driver error, and returns the message shown by /sd/ready,
+// the driver errors contain the internal addresses.
+func publicError(name string, err error, message string) error {
+	log.Errorf(err, "Health check %s failed.", name)
+	if errors.Is(err, context.DeadlineExceeded) {
+		message += ": timeout"
+	}
+	return errors.New(message)
+}
+
+func unregisterHealthChecks() {
+	health.Unregister("database")
+	health.Unregister("migrations")
+}
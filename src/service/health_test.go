@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/moocss/apiserver/src/pkg/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseHealthCheck(t *testing.T) {
+	db := openMemoryDB(t)
+	registerHealthChecks(db)
+	defer unregisterHealthChecks()
+
+	report := health.Run(context.Background())
+	assert.True(t, report.OK())
+
+	// The driver error isn't shown.
+	db.Close()
+	report = health.Run(context.Background())
+	assert.False(t, report.OK())
+	assert.Equal(t, "database is unreachable", report.Checks[0].Error)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moocss/apiserver/src/model"
+)
+
+// ErrDuplicateUsername is returned by the in-memory repository, as the unique key of MySQL does.
+var ErrDuplicateUsername = errors.New("duplicate username")
+
+// memoryUserRepository stores the users in memory, it's meant for the unit tests.
+type memoryUserRepository struct {
+	mutex  *sync.RWMutex
+	nextID uint64
+	users  map[uint64]model.UserModel
+}
+
+// NewMemoryUserRepository returns an empty in-memory UserRepository.
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{
+		mutex:  &sync.RWMutex{},
+		nextID: 1,
+		users:  make(map[uint64]model.UserModel),
+	}
+}
+
+func (r *memoryUserRepository) Create(user *model.UserModel) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, u := range r.users {
+		if u.Username == user.Username {
+			return ErrDuplicateUsername
+		}
+	}
+
+	now := time.Now()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.nextID++
+
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) Get(id uint64) (*model.UserModel, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return &u, nil
+}
+
+func (r *memoryUserRepository) GetByName(username string) (*model.UserModel, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, u := range r.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (r *memoryUserRepository) List(username string, offset, limit int) ([]*model.UserModel, uint64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	matched := make([]*model.UserModel, 0)
+	for _, u := range r.users {
+		if strings.Contains(u.Username, username) {
+			u := u
+			matched = append(matched, &u)
+		}
+	}
+
+	// Same order as the database repository.
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID > matched[j].ID
+	})
+
+	count := uint64(len(matched))
+	if offset >= len(matched) {
+		return []*model.UserModel{}, count, nil
+	}
+	end := offset + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	return matched[offset:end], count, nil
+}
+
+func (r *memoryUserRepository) Update(user *model.UserModel) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.users[user.ID]; !ok {
+		return ErrRecordNotFound
+	}
+	for id, u := range r.users {
+		if id != user.ID && u.Username == user.Username {
+			return ErrDuplicateUsername
+		}
+	}
+
+	user.UpdatedAt = time.Now()
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) Delete(id uint64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.users, id)
+	return nil
+}
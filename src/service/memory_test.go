@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/moocss/apiserver/src/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	assert := assert.New(t)
+	srv := NewUserService(NewMemoryUserRepository())
+
+	for _, name := range []string{"admin", "alice", "bob"} {
+		assert.Nil(srv.CreateUser(&model.UserModel{Username: name, Password: "123456"}))
+	}
+	assert.Equal(ErrDuplicateUsername, srv.CreateUser(&model.UserModel{Username: "bob", Password: "123456"}))
+
+	u := srv.GetUserByName("alice")
+	assert.NotNil(u)
+	assert.Equal(uint64(2), u.ID)
+
+	users, count, err := srv.GetUserList("", 1, 1)
+	assert.Nil(err)
+	assert.Equal(uint64(3), count)
+	assert.Equal("alice", users[0].Username)
+
+	users, count, err = srv.GetUserList("a", 0, 0)
+	assert.Nil(err)
+	assert.Equal(uint64(2), count)
+	assert.Len(users, 2)
+
+	u.Username = "carol"
+	assert.Nil(srv.UpdateUser(u))
+	assert.Nil(srv.GetUserByName("alice"))
+	assert.Equal("carol", srv.GetUser(2).Username)
+
+	assert.Nil(srv.DeleteUser(2))
+	assert.Nil(srv.GetUser(2))
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lexkong/log"
+	"github.com/lexkong/log/lager"
+)
+
+// ReplicaPool routes the reads to the healthy read replicas in round robin,
+// and falls back to the primary when all the replicas are unhealthy.
+type ReplicaPool struct {
+	primary  *gorm.DB
+	replicas []*replica
+	next     uint32
+
+	once sync.Once
+	done chan struct{}
+}
+
+// replica is a read replica and its health.
+type replica struct {
+	addr    string
+	db      *gorm.DB
+	healthy int32
+}
+
+// NewReplicaPool returns the pool of the primary without replicas.
+func NewReplicaPool(primary *gorm.DB) *ReplicaPool {
+	return &ReplicaPool{
+		primary: primary,
+		done:    make(chan struct{}),
+	}
+}
+
+// Add adds a replica, it's used after the next health check if it isn't healthy yet.
+func (p *ReplicaPool) Add(addr string, db *gorm.DB, healthy bool) {
+	r := &replica{addr: addr, db: db}
+	if healthy {
+		r.healthy = 1
+	}
+	p.replicas = append(p.replicas, r)
+}
+
+// Primary returns the primary, used by the writes.
+func (p *ReplicaPool) Primary() *gorm.DB {
+	return p.primary
+}
+
+// Reader returns the next healthy replica, or the primary if there isn't any.
+func (p *ReplicaPool) Reader() *gorm.DB {
+	n := len(p.replicas)
+	for i := 0; i < n; i++ {
+		r := p.replicas[int((atomic.AddUint32(&p.next, 1)-1)%uint32(n))]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			return r.db
+		}
+	}
+	return p.primary
+}
+
+// Check pings the replicas and updates their health.
+func (p *ReplicaPool) Check() {
+	for _, r := range p.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := r.db.DB().PingContext(ctx)
+		cancel()
+
+		var healthy int32
+		if err == nil {
+			healthy = 1
+		}
+		if atomic.SwapInt32(&r.healthy, healthy) == healthy {
+			continue
+		}
+		if err != nil {
+			log.Error("Database replica is unhealthy, the reads are routed to the others.", err, lager.Data{"addr": r.addr})
+		} else {
+			log.Info("Database replica is healthy again.", lager.Data{"addr": r.addr})
+		}
+	}
+}
+
+// Watch checks the replicas every interval until the pool is closed.
+func (p *ReplicaPool) Watch(interval time.Duration) {
+	if len(p.replicas) == 0 || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.Check()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the health checks and closes the replicas, the primary is closed by the caller.
+func (p *ReplicaPool) Close() {
+	p.once.Do(func() {
+		close(p.done)
+		for _, r := range p.replicas {
+			if err := r.db.Close(); err != nil {
+				log.Error("Disconnect from database replica failed.", err, lager.Data{"addr": r.addr})
+			}
+		}
+	})
+}
+
+type sessionKey struct{}
+
+// session records the writes of a request.
+type session struct {
+	wrote int32
+}
+
+// WithSession returns the context of a request, the reads after a write in the request
+// are pinned to the primary, so the request reads its own writes despite the replication lag.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// markWrite pins the session of the context to the primary.
+func markWrite(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		atomic.StoreInt32(&s.wrote, 1)
+	}
+}
+
+// pinned reports whether the session of the context has written.
+func pinned(ctx context.Context) bool {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return ok && atomic.LoadInt32(&s.wrote) == 1
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/moocss/apiserver/src/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func openMemoryDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	db.DB().SetMaxOpenConns(1)
+	db.AutoMigrate(&model.UserModel{})
+	return db
+}
+
+func TestReplicaPoolReader(t *testing.T) {
+	primary, r1, r2 := openMemoryDB(t), openMemoryDB(t), openMemoryDB(t)
+	defer primary.Close()
+
+	pool := NewReplicaPool(primary)
+	assert.Equal(t, primary, pool.Reader())
+
+	pool.Add("r1", r1, true)
+	pool.Add("r2", r2, true)
+
+	// Round robin between the healthy replicas.
+	assert.Equal(t, r1, pool.Reader())
+	assert.Equal(t, r2, pool.Reader())
+	assert.Equal(t, r1, pool.Reader())
+
+	// The unhealthy replica is skipped.
+	r2.Close()
+	pool.Check()
+	assert.Equal(t, r1, pool.Reader())
+	assert.Equal(t, r1, pool.Reader())
+
+	// Fall back to the primary when all the replicas are unhealthy.
+	r1.Close()
+	pool.Check()
+	assert.Equal(t, primary, pool.Reader())
+}
+
+func TestReadAfterWrite(t *testing.T) {
+	primary, replica := openMemoryDB(t), openMemoryDB(t)
+	defer primary.Close()
+	defer replica.Close()
+
+	pool := NewReplicaPool(primary)
+	pool.Add("replica", replica, true)
+	srv := NewUserService(NewGormUserRepository(pool))
+
+	// The replica lags behind the primary.
+	ctx := WithSession(context.Background())
+	assert.Nil(t, srv.WithContext(ctx).GetUserByName("admin"))
+
+	assert.NoError(t, srv.WithContext(ctx).CreateUser(&model.UserModel{Username: "admin", Password: "admin"}))
+
+	// The request reads its own write from the primary.
+	assert.NotNil(t, srv.WithContext(ctx).GetUserByName("admin"))
+
+	// The other requests read from the replica.
+	assert.Nil(t, srv.WithContext(WithSession(context.Background())).GetUserByName("admin"))
+}
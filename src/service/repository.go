@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/moocss/apiserver/src/model"
+)
+
+// ErrRecordNotFound is returned by the repositories when the record doesn't exist.
+var ErrRecordNotFound = errors.New("record not found")
+
+// ErrInvalidID is returned for the id 0, GORM updates or deletes every row without the primary key.
+var ErrInvalidID = errors.New("invalid id")
+
+// UserRepository is the storage of the users.
+type UserRepository interface {
+	Create(user *model.UserModel) error
+	Get(id uint64) (*model.UserModel, error)
+	GetByName(username string) (*model.UserModel, error)
+	List(username string, offset, limit int) ([]*model.UserModel, uint64, error)
+	Update(user *model.UserModel) error
+	Delete(id uint64) error
+}
+
+// contextRepository is implemented by the repositories routing the queries by the request context.
+type contextRepository interface {
+	WithContext(ctx context.Context) UserRepository
+}
+
+// gormUserRepository stores the users with GORM, it serves both MySQL and SQLite.
+// The reads go to the replicas of the pool, the writes go to the primary.
+type gormUserRepository struct {
+	pool *ReplicaPool
+	ctx  context.Context
+}
+
+// NewGormUserRepository returns the UserRepository backed by the database.
+func NewGormUserRepository(pool *ReplicaPool) UserRepository {
+	return &gormUserRepository{pool: pool, ctx: context.Background()}
+}
+
+// WithContext returns the repository reading from the primary after a write in the request session.
+func (r *gormUserRepository) WithContext(ctx context.Context) UserRepository {
+	return &gormUserRepository{pool: r.pool, ctx: ctx}
+}
+
+func (r *gormUserRepository) reader() *gorm.DB {
+	if pinned(r.ctx) {
+		return r.pool.Primary()
+	}
+	return r.pool.Reader()
+}
+
+func (r *gormUserRepository) writer() *gorm.DB {
+	markWrite(r.ctx)
+	return r.pool.Primary()
+}
+
+func (r *gormUserRepository) Create(user *model.UserModel) error {
+	tx := r.writer().Begin()
+	if err := tx.Create(user).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (r *gormUserRepository) Get(id uint64) (*model.UserModel, error) {
+	u := &model.UserModel{}
+
+	if err := r.reader().First(u, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return u, nil
+}
+
+func (r *gormUserRepository) GetByName(username string) (*model.UserModel, error) {
+	u := &model.UserModel{}
+
+	if err := r.reader().Where("`username` = ?", username).First(u).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return u, nil
+}
+
+func (r *gormUserRepository) List(username string, offset, limit int) ([]*model.UserModel, uint64, error) {
+	var count uint64
+	users := make([]*model.UserModel, 0)
+
+	query := r.reader().Model(&model.UserModel{})
+	if username != "" {
+		query = query.Where("`username` LIKE ? ESCAPE "+likeEscape(query), "%"+escapeLike(username)+"%")
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return users, count, err
+	}
+
+	if err := query.
+		Select("`id`, `username`, `createdAt`, `updatedAt`").
+		Order("`id` DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&users).Error; err != nil {
+
+		return users, count, err
+	}
+
+	return users, count, nil
+}
+
+func (r *gormUserRepository) Update(user *model.UserModel) error {
+	if user.ID == 0 {
+		return ErrInvalidID
+	}
+
+	tx := r.writer().Begin()
+	if err := tx.Save(user).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (r *gormUserRepository) Delete(id uint64) error {
+	if id == 0 {
+		return ErrInvalidID
+	}
+
+	user := model.UserModel{}
+	user.ID = id
+
+	tx := r.writer().Begin()
+	if err := tx.Delete(&user).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// notFound translates the GORM not found error to ErrRecordNotFound.
+func notFound(err error) error {
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrRecordNotFound
+	}
+	return err
+}
+
+// escapeLike escapes the wildcards of the LIKE pattern with a backslash.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// likeEscape returns the backslash literal of the ESCAPE clause, MySQL escapes the backslashes in strings.
+func likeEscape(db *gorm.DB) string {
+	if db.Dialect().GetName() == "mysql" {
+		return `'\\'`
+	}
+	return `'\'`
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteWithoutID(t *testing.T) {
+	// The idle connection keeps the memory database.
+	db, err := openDB(&config.SectionDb{MaxIdleConns: 1}, "sqlite3", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+	db.AutoMigrate(&model.UserModel{})
+
+	srv := NewUserService(NewGormUserRepository(NewReplicaPool(db)))
+	assert.NoError(t, srv.CreateUser(&model.UserModel{Username: "admin", Password: "123456"}))
+
+	assert.Equal(t, ErrInvalidID, srv.DeleteUser(0))
+	assert.Equal(t, ErrInvalidID, srv.UpdateUser(&model.UserModel{Username: "admin"}))
+	// GORM refuses to delete every row.
+	assert.Error(t, db.Delete(&model.UserModel{}).Error)
+	assert.NotNil(t, srv.GetUserByName("admin"))
+}
+
+func TestListEscapesWildcards(t *testing.T) {
+	db := openMemoryDB(t)
+	defer db.Close()
+
+	srv := NewUserService(NewGormUserRepository(NewReplicaPool(db)))
+	for _, name := range []string{"admin", "a_b", "a%b", `a\b`} {
+		assert.NoError(t, srv.CreateUser(&model.UserModel{Username: name, Password: "123456"}))
+	}
+
+	for _, name := range []string{"_", "%", `\`} {
+		users, count, err := srv.GetUserList(name, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), count, name)
+		assert.Equal(t, "a"+name+"b", users[0].Username)
+	}
+}
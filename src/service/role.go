@@ -0,0 +1,128 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/jinzhu/gorm"
+	"github.com/moocss/apiserver/src/model"
+	"github.com/moocss/apiserver/src/pkg/errno"
+)
+
+// Role service
+var Role = &roleService{
+	mutex: &sync.Mutex{},
+}
+
+type roleService struct {
+	mutex *sync.Mutex
+}
+
+// CreateRole creates the role with its permissions.
+func (srv *roleService) CreateRole(role *model.RoleModel) error {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	if r := srv.GetRoleByName(role.Name); r != nil {
+		return errno.ErrRoleExists
+	}
+
+	tx := DB.Self.Begin()
+	if err := tx.Create(role).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (srv *roleService) GetRoleByName(name string) *model.RoleModel {
+	r := &model.RoleModel{}
+
+	if err := DB.Self.Preload("Permissions").Where("`name` = ?", name).First(r).Error; err != nil {
+		return nil
+	}
+	return r
+}
+
+func (srv *roleService) ListRoles() ([]*model.RoleModel, error) {
+	roles := make([]*model.RoleModel, 0)
+
+	if err := DB.Self.Preload("Permissions").Order("`id` ASC").Find(&roles).Error; err != nil {
+		return roles, err
+	}
+	return roles, nil
+}
+
+// GetUserRoles returns the roles assigned to the user.
+func (srv *roleService) GetUserRoles(userId uint64) ([]*model.RoleModel, error) {
+	roles := make([]*model.RoleModel, 0)
+
+	if err := DB.Self.Preload("Permissions").
+		Joins("JOIN `tb_user_roles` ON `tb_user_roles`.`roleId` = `tb_roles`.`id`").
+		Where("`tb_user_roles`.`userId` = ?", userId).
+		Find(&roles).Error; err != nil {
+		return roles, err
+	}
+	return roles, nil
+}
+
+// AssignRole assigns the role to the user, assigning a role twice is a no-op.
+func (srv *roleService) AssignRole(userId uint64, name string) error {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	r := srv.GetRoleByName(name)
+	if r == nil {
+		return errno.ErrRoleNotFound
+	}
+
+	tx := DB.Self.Begin()
+	ur := model.UserRoleModel{}
+	err := tx.Where("`userId` = ? AND `roleId` = ?", userId, r.ID).First(&ur).Error
+	if err == nil {
+		tx.Rollback()
+		return nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(&model.UserRoleModel{UserID: userId, RoleID: r.ID}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// RevokeRole removes the role from the user.
+func (srv *roleService) RevokeRole(userId uint64, name string) error {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	r := srv.GetRoleByName(name)
+	if r == nil {
+		return errno.ErrRoleNotFound
+	}
+
+	tx := DB.Self.Begin()
+	if err := tx.Where("`userId` = ? AND `roleId` = ?", userId, r.ID).Delete(&model.UserRoleModel{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// HasPermission reports whether one of the user roles grants the permission.
+func (srv *roleService) HasPermission(userId uint64, permission string) (bool, error) {
+	roles, err := srv.GetUserRoles(userId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range roles {
+		if r.Can(permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/moocss/apiserver/src/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedRoles(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+	db.AutoMigrate(&model.RoleModel{}, &model.RolePermissionModel{}, &model.UserRoleModel{})
+
+	// Seeding twice is a no-op.
+	assert.NoError(t, seedRoles(db))
+	assert.NoError(t, seedRoles(db))
+
+	// No user gets a role until it's assigned.
+	DB = &Database{Self: db}
+	ok, err := Role.HasPermission(1, "roles:manage")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, Role.AssignRole(1, "admin"))
+	ok, err = Role.HasPermission(1, "roles:manage")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestValidatePermissions(t *testing.T) {
+	role := &model.RoleModel{Name: "editor"}
+	for _, p := range []string{"*", "users:get", "users:*", "roles:manage"} {
+		role.Permissions = []model.RolePermissionModel{{Permission: p}}
+		assert.NoError(t, Validate(role), p)
+	}
+	for _, p := range []string{"", "users", "users:fly", "posts:*", "*:*"} {
+		role.Permissions = []model.RolePermissionModel{{Permission: p}}
+		assert.Error(t, Validate(role), p)
+	}
+}
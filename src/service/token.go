@@ -0,0 +1,187 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/model"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/moocss/apiserver/src/pkg/token"
+	"github.com/satori/go.uuid"
+)
+
+// Token service
+var Token = &tokenService{
+	mutex: &sync.Mutex{},
+}
+
+type tokenService struct {
+	mutex *sync.Mutex
+}
+
+// newRefreshToken generates a refresh token of the family, returns the raw token and the model to be stored.
+func newRefreshToken(userId uint64, family string) (string, *model.RefreshTokenModel, error) {
+	raw, err := token.NewOpaque()
+	if err != nil {
+		return "", nil, err
+	}
+
+	t := &model.RefreshTokenModel{
+		UserID:    userId,
+		Family:    family,
+		TokenHash: token.Hash(raw),
+		ExpiresAt: time.Now().Add(refreshTokenTimeout()),
+	}
+	return raw, t, nil
+}
+
+// refreshTokenTimeout returns the lifetime of the refresh tokens in the current config.
+func refreshTokenTimeout() time.Duration {
+	if conf := config.Current(); conf != nil {
+		return conf.Core.RefreshTokenTimeout
+	}
+	return 0
+}
+
+// revokeFamily revokes all the active tokens of the family.
+func revokeFamily(tx *gorm.DB, family string) *gorm.DB {
+	return tx.Model(&model.RefreshTokenModel{}).
+		Where("`family` = ? AND `revokedAt` IS NULL", family).
+		Update("revokedAt", time.Now())
+}
+
+// Session is a token family of the user, ExpiresAt is the expiry of its active refresh token.
+type Session struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// IssueRefreshToken starts a new token family (session) for the user, the family is the session id.
+func (srv *tokenService) IssueRefreshToken(userId uint64) (*model.RefreshTokenModel, string, error) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	u4, err := uuid.NewV4()
+	if err != nil {
+		return nil, "", err
+	}
+
+	raw, t, err := newRefreshToken(userId, u4.String())
+	if err != nil {
+		return nil, "", err
+	}
+
+	tx := DB.Self.Begin()
+	if err := tx.Create(t).Error; err != nil {
+		tx.Rollback()
+		return nil, "", err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, "", err
+	}
+	return t, raw, nil
+}
+
+// RotateRefreshToken exchanges the refresh token for a new one of the same family.
+// Presenting a token which was already rotated revokes the whole family.
+func (srv *tokenService) RotateRefreshToken(raw string) (*model.RefreshTokenModel, string, error) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	old := &model.RefreshTokenModel{}
+
+	tx := DB.Self.Begin()
+	if err := tx.Where("`tokenHash` = ?", token.Hash(raw)).First(old).Error; err != nil {
+		tx.Rollback()
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, "", errno.ErrTokenInvalid
+		}
+		return nil, "", err
+	}
+
+	if old.Expired() {
+		tx.Rollback()
+		return nil, "", errno.ErrTokenExpired
+	}
+
+	// Only one caller may rotate a token, anyone else is replaying it.
+	res := tx.Model(old).Where("`revokedAt` IS NULL").Update("revokedAt", time.Now())
+	if res.Error != nil {
+		tx.Rollback()
+		return nil, "", res.Error
+	}
+	if res.RowsAffected == 0 {
+		if err := revokeFamily(tx, old.Family).Error; err != nil {
+			tx.Rollback()
+			return nil, "", err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return nil, "", err
+		}
+		return nil, "", errno.ErrTokenReused
+	}
+
+	next, t, err := newRefreshToken(old.UserID, old.Family)
+	if err != nil {
+		tx.Rollback()
+		return nil, "", err
+	}
+	if err := tx.Create(t).Error; err != nil {
+		tx.Rollback()
+		return nil, "", err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, "", err
+	}
+
+	return t, next, nil
+}
+
+// RevokeRefreshToken revokes the family of the refresh token owned by the user.
+func (srv *tokenService) RevokeRefreshToken(userId uint64, raw string) error {
+	t := &model.RefreshTokenModel{}
+	if err := DB.Self.Where("`tokenHash` = ? AND `userId` = ?", token.Hash(raw), userId).First(t).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return errno.ErrTokenInvalid
+		}
+		return err
+	}
+
+	return srv.RevokeSession(userId, t.Family)
+}
+
+// RevokeSession revokes the token family (session) owned by the user.
+func (srv *tokenService) RevokeSession(userId uint64, family string) error {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	tx := DB.Self.Begin()
+	res := revokeFamily(tx.Where("`userId` = ?", userId), family)
+	if res.Error != nil {
+		tx.Rollback()
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		tx.Rollback()
+		return errno.ErrSessionNotFound
+	}
+	return tx.Commit().Error
+}
+
+// ListSessions returns the active sessions of the user, the sessions whose refresh token is neither revoked nor expired.
+func (srv *tokenService) ListSessions(userId uint64) ([]*Session, error) {
+	tokens := make([]*model.RefreshTokenModel, 0)
+	if err := DB.Self.Where("`userId` = ? AND `revokedAt` IS NULL AND `expiresAt` > ?", userId, time.Now()).
+		Order("`id`").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(tokens))
+	for _, t := range tokens {
+		sessions = append(sessions, &Session{ID: t.Family, CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt})
+	}
+	return sessions, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/model"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTokenDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	db.DB().SetMaxOpenConns(1)
+	db.AutoMigrate(&model.RefreshTokenModel{})
+
+	config.Store(&config.ConfYaml{Core: config.SectionCore{RefreshTokenTimeout: time.Hour}})
+	DB = &Database{Self: db}
+	return db
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	db := setupTokenDB(t)
+	defer db.Close()
+
+	session, raw, err := Token.IssueRefreshToken(1)
+	assert.NoError(t, err)
+
+	// The rotated token stays in the session.
+	next, nextRaw, err := Token.RotateRefreshToken(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Family, next.Family)
+	assert.NotEqual(t, raw, nextRaw)
+
+	sessions, err := Token.ListSessions(1)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, session.Family, sessions[0].ID)
+
+	// Replaying the rotated token revokes the whole session.
+	_, _, err = Token.RotateRefreshToken(raw)
+	assert.Equal(t, errno.ErrTokenReused, err)
+	_, _, err = Token.RotateRefreshToken(nextRaw)
+	assert.Equal(t, errno.ErrTokenReused, err)
+
+	sessions, err = Token.ListSessions(1)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 0)
+
+	_, _, err = Token.RotateRefreshToken("unknown")
+	assert.Equal(t, errno.ErrTokenInvalid, err)
+}
+
+func TestRevokeSession(t *testing.T) {
+	db := setupTokenDB(t)
+	defer db.Close()
+
+	session, raw, err := Token.IssueRefreshToken(1)
+	assert.NoError(t, err)
+
+	// Only the owner revokes the session.
+	assert.Equal(t, errno.ErrSessionNotFound, Token.RevokeSession(2, session.Family))
+	assert.NoError(t, Token.RevokeSession(1, session.Family))
+	assert.Equal(t, errno.ErrSessionNotFound, Token.RevokeSession(1, session.Family))
+
+	_, _, err = Token.RotateRefreshToken(raw)
+	assert.Equal(t, errno.ErrTokenReused, err)
+}
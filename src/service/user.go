@@ -1,123 +1,115 @@
 package service
 
 import (
+	"context"
 	"sync"
 	"github.com/moocss/apiserver/src/model"
 	"github.com/moocss/apiserver/src/pkg/auth"
-	validator "gopkg.in/go-playground/validator.v9"
 )
 
-// User service
-var User = &userService{
-	mutex: &sync.Mutex{},
-}
+// User service, it's set up with the configured repository by DB.Init.
+var User *UserService
 
-type userService struct {
+// UserService implements the user business logic on top of a UserRepository.
+type UserService struct {
 	mutex *sync.Mutex
+	repo  UserRepository
 }
 
 const (
-	pageSize = 20
+	// 分页默认和最大的条数
+	defaultLimit = 20
+	maxLimit     = 100
 )
 
-func (srv *userService) CreateUser(user *model.UserModel) error {
-	srv.mutex.Lock()
-	defer  srv.mutex.Unlock()
-
-	tx := DB.Self.Begin()
-	if err := tx.Create(&user).Error; err != nil {
-		tx.Rollback()
-		return err
+// NewUserService returns the user service stored in the repository.
+func NewUserService(repo UserRepository) *UserService {
+	return &UserService{
+		mutex: &sync.Mutex{},
+		repo:  repo,
 	}
-	tx.Commit()
-	return nil
 }
 
-func (srv *userService) DeleteUser(id uint64) error  {
-	srv.mutex.Lock()
-	defer srv.mutex.Unlock()
-
-	user := model.UserModel{}
-	user.ID = id
+// WithContext returns the service bound to the request context,
+// the reads after a write in the request use the primary database.
+func (srv *UserService) WithContext(ctx context.Context) *UserService {
+	repo, ok := srv.repo.(contextRepository)
+	if !ok {
+		return srv
+	}
 
-	tx := DB.Self.Begin()
-	if err := tx.Delete(&user).Error; err != nil {
-		tx.Rollback()
-		return err
+	return &UserService{
+		mutex: srv.mutex,
+		repo:  repo.WithContext(ctx),
 	}
-	tx.Commit()
-	return nil
 }
 
-func (srv *userService) UpdateUser(user *model.UserModel) error  {
+func (srv *UserService) CreateUser(user *model.UserModel) error {
 	srv.mutex.Lock()
 	defer  srv.mutex.Unlock()
 
-	tx := DB.Self.Begin()
-	if err := tx.Save(user).Error; err != nil {
-		tx.Rollback()
+	return srv.repo.Create(user)
+}
 
-		return  err
-	}
-	tx.Commit()
+func (srv *UserService) DeleteUser(id uint64) error  {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
 
-	return nil
+	return srv.repo.Delete(id)
 }
 
-func (srv *userService) GetUser(id uint64) *model.UserModel  {
-	u := &model.UserModel{}
+func (srv *UserService) UpdateUser(user *model.UserModel) error  {
+	srv.mutex.Lock()
+	defer  srv.mutex.Unlock()
+
+	return srv.repo.Update(user)
+}
 
-	if err := DB.Self.First(&u, id).Error; err != nil {
+func (srv *UserService) GetUser(id uint64) *model.UserModel  {
+	u, err := srv.repo.Get(id)
+	if err != nil {
 		return nil
 	}
 
 	return u
 }
 
-func (srv *userService) GetUserByName(username string) *model.UserModel  {
-	u := &model.UserModel{}
-
-	if err := DB.Self.Where("`username` = ?", username).First(&u).Error; err != nil {
+func (srv *UserService) GetUserByName(username string) *model.UserModel  {
+	u, err := srv.repo.GetByName(username)
+	if err != nil {
 		return nil
 	}
 	return u
 }
 
-func GetUserList(username string, page int) ([]*model.UserModel, uint64, error) {
-	offset := (page - 1) * pageSize
-	var count uint64
-
-	users := make([]*model.UserModel, 0)
-
-	if err := DB.Self.Model(&model.UserModel{}).
-		Select("`id`, `username`, `created_at`").
-		Where("`username` LIKE ?", username).
-		Order("`id` DESC").
-		Count(&count).
-		Offset(offset).
-		Limit(pageSize).
-		Find(&users).Error; err != nil {
-
-		return users, count, err
+// GetUserList returns the users whose username contains the keyword, and the total count of them.
+func (srv *UserService) GetUserList(username string, offset, limit int) ([]*model.UserModel, uint64, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
 	}
 
-	return users, count, nil
+	return srv.repo.List(username, offset, limit)
 }
 
 // Compare with the plain text password. Returns true if it's the same as the encrypted one (in the `User` struct).
-func (srv *userService) Compare(u *model.UserModel, pwd string) (err error) {
+func (srv *UserService) Compare(u *model.UserModel, pwd string) (err error) {
 	err = auth.Compare(u.Password, pwd)
 	return
 }
 
 // Encrypt the user password.
-func (srv *userService) Encrypt(u *model.UserModel) (err error) {
+func (srv *UserService) Encrypt(u *model.UserModel) (err error) {
 	u.Password, err = auth.Encrypt(u.Password)
 	return
 }
 
 // Validate the fields.
-func (srv *userService) Validate(u *model.UserModel) error {
-	validate := validator.New()
-	return validate.Struct(u)
+func (srv *UserService) Validate(u *model.UserModel) error {
+	return Validate(u)
 }
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/moocss/apiserver/src/pkg/constvar"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// validate reports the invalid fields by the json names, as the clients send them.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	v.RegisterValidation("permission", func(fl validator.FieldLevel) bool {
+		return constvar.ValidPermission(fl.Field().String())
+	})
+	return v
+}
+
+// Validate validates the fields of the struct, the error is a validator.ValidationErrors.
+func Validate(s interface{}) error {
+	return validate.Struct(s)
+}
@@ -0,0 +1,147 @@
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/config"
+	"github.com/moocss/apiserver/src/pkg/errno"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// Language negotiates the language of the response: the `lang` query parameter,
+// then the `Accept-Language` header, then `core.language`, then errno.DefaultLanguage.
+func Language(c *gin.Context) string {
+	if lang := supported(c.Query("lang")); lang != "" {
+		return lang
+	}
+	for _, tag := range acceptLanguages(c.GetHeader("Accept-Language")) {
+		if lang := supported(tag); lang != "" {
+			return lang
+		}
+	}
+	if conf := config.Current(); conf != nil {
+		if lang := supported(conf.Core.Language); lang != "" {
+			return lang
+		}
+	}
+	return errno.DefaultLanguage
+}
+
+// supported returns the supported language matching the tag, e.g. zh, zh-cn and zh_Hans_CN are zh-CN.
+func supported(tag string) string {
+	tag = strings.ToLower(strings.Replace(strings.TrimSpace(tag), "_", "-", -1))
+	if tag == "" {
+		return ""
+	}
+	base := strings.SplitN(tag, "-", 2)[0]
+
+	for _, lang := range errno.Languages() {
+		if strings.ToLower(lang) == tag {
+			return lang
+		}
+	}
+	for _, lang := range errno.Languages() {
+		if strings.SplitN(strings.ToLower(lang), "-", 2)[0] == base {
+			return lang
+		}
+	}
+	return ""
+}
+
+// acceptLanguages returns the language tags of the Accept-Language header ordered by the quality.
+func acceptLanguages(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" || fields[0] == "*" {
+			continue
+		}
+
+		t := tag{name: fields[0], q: 1}
+		for _, f := range fields[1:] {
+			if v := strings.TrimSpace(f); strings.HasPrefix(v, "q=") {
+				if q, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		if t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.name)
+	}
+	return names
+}
+
+// fieldMessages are the messages of the validator rules, the `min`, `max` and `len` of the strings and the slices
+// are about the length. The arguments are the field and the param.
+var fieldMessages = map[string]map[string]string{
+	"en": {
+		"required":   "%s is required",
+		"min":        "%s must be at least %s",
+		"min.length": "%s must be at least %s characters",
+		"max":        "%s must be at most %s",
+		"max.length": "%s must be at most %s characters",
+		"len":        "%s must be %s",
+		"len.length": "%s must be %s characters",
+		"email":      "%s must be a valid email address",
+		"oneof":      "%s must be one of [%s]",
+		"permission": "%s is not a known permission",
+		"":           "%s is invalid",
+	},
+	"zh-CN": {
+		"required":   "%s不能为空",
+		"min":        "%s不能小于%s",
+		"min.length": "%s的长度不能少于%s个字符",
+		"max":        "%s不能大于%s",
+		"max.length": "%s的长度不能超过%s个字符",
+		"len":        "%s必须等于%s",
+		"len.length": "%s的长度必须为%s个字符",
+		"email":      "%s必须是有效的邮箱地址",
+		"oneof":      "%s必须是[%s]中的一个",
+		"permission": "%s不是已知的权限",
+		"":           "%s格式不正确",
+	},
+}
+
+// fieldMessage returns the message of the failed rule in the language.
+func fieldMessage(e validator.FieldError, lang string) string {
+	msgs, ok := fieldMessages[lang]
+	if !ok {
+		msgs = fieldMessages[errno.DefaultLanguage]
+	}
+
+	key := e.Tag()
+	switch e.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if _, ok := msgs[key+".length"]; ok {
+			key += ".length"
+		}
+	}
+
+	format, ok := msgs[key]
+	if !ok {
+		return fmt.Sprintf(msgs[""], e.Field())
+	}
+	if strings.Count(format, "%s") == 1 {
+		return fmt.Sprintf(format, e.Field())
+	}
+	return fmt.Sprintf(format, e.Field(), e.Param())
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lexkong/log"
+	"github.com/lexkong/log/lager"
+)
+
+// RequestLogger logs with the request ID and the user ID of the request.
+type RequestLogger struct {
+	data lager.Data
+}
+
+// Logger returns the logger of the request.
+func Logger(c *gin.Context) *RequestLogger {
+	data := lager.Data{"request_id": GetReqID(c)}
+	if id := GetUserID(c); id != 0 {
+		data["user_id"] = id
+	}
+	return &RequestLogger{data: data}
+}
+
+// with merges the data of the request into the data of the log line.
+func (l *RequestLogger) with(data []lager.Data) lager.Data {
+	merged := lager.Data{}
+	for k, v := range l.data {
+		merged[k] = v
+	}
+	for _, d := range data {
+		for k, v := range d {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func (l *RequestLogger) Debug(action string, data ...lager.Data) {
+	log.Debug(action, l.with(data))
+}
+
+func (l *RequestLogger) Info(action string, data ...lager.Data) {
+	log.Info(action, l.with(data))
+}
+
+func (l *RequestLogger) Warn(action string, data ...lager.Data) {
+	log.Warn(action, l.with(data))
+}
+
+func (l *RequestLogger) Error(action string, err error, data ...lager.Data) {
+	log.Error(action, err, l.with(data))
+}
@@ -1,24 +1,103 @@
 package util
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/moocss/apiserver/src/config"
 	"github.com/moocss/apiserver/src/pkg/errno"
+	validator "gopkg.in/go-playground/validator.v9"
 )
 
+// ProblemContentType is the media type of the RFC 7807 error responses.
+const ProblemContentType = "application/problem+json"
+
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
+	Code    int          `json:"code"`
+	Message string       `json:"message"`
+	Data    interface{}  `json:"data"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// Problem is the RFC 7807 error response, it's sent if the client accepts application/problem+json.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Instance  string       `json:"instance"`
+	Code      int          `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
+// FieldError is an invalid field and the rule it failed, e.g. {"field": "password", "rule": "min", "param": "5"}.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// SendResponse sends the data, or the error with the message in the language of the client.
 func SendResponse(c *gin.Context, err error, data interface{}) {
-	code, message := errno.DecodeErr(err)
+	lang := Language(c)
+	code, message := errno.DecodeErrIn(err, lang)
+	fields := fieldErrors(err, lang)
+	c.Header("Content-Language", lang)
+	// The caches keep a response per language, the CORS middleware may have set Vary: Origin.
+	c.Writer.Header().Add("Vary", "Accept-Language")
+
+	if err != nil && acceptsProblem(c) {
+		status := errno.HTTPStatus(err)
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(status, Problem{
+			Type:      fmt.Sprintf("urn:apiserver:errno:%d", code),
+			Title:     message,
+			Status:    status,
+			Instance:  c.Request.URL.Path,
+			Code:      code,
+			RequestID: GetReqID(c),
+			Errors:    fields,
+		})
+		return
+	}
 
-	// always return http.StatusOK
-	c.JSON(http.StatusOK, Response{
+	// The status code of the errno, or always http.StatusOK for the legacy clients.
+	status := errno.HTTPStatus(err)
+	if conf := config.Current(); conf != nil && conf.Core.AlwaysOK {
+		status = http.StatusOK
+	}
+
+	c.JSON(status, Response{
 		Code:    code,
 		Message: message,
 		Data:    data,
+		Errors:  fields,
 	})
 }
+
+func acceptsProblem(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), ProblemContentType)
+}
+
+// fieldErrors returns the invalid fields of the validator.ValidationErrors wrapped in the error.
+func fieldErrors(err error, lang string) []FieldError {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+
+	fields := make([]FieldError, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, FieldError{
+			Field:   e.Field(),
+			Rule:    e.Tag(),
+			Param:   e.Param(),
+			Message: fieldMessage(e, lang),
+		})
+	}
+	return fields
+}
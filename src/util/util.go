@@ -20,3 +20,24 @@ func GetReqID(c *gin.Context) string {
 	return ""
 }
 
+func GetUserID(c *gin.Context) uint64 {
+	v, ok := c.Get("X-User-Id")
+	if !ok {
+		return 0
+	}
+	if userId, ok := v.(uint64); ok {
+		return userId
+	}
+	return 0
+}
+
+func GetUsername(c *gin.Context) string {
+	v, ok := c.Get("X-Username")
+	if !ok {
+		return ""
+	}
+	if username, ok := v.(string); ok {
+		return username
+	}
+	return ""
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moocss/apiserver/src/model"
+	"github.com/moocss/apiserver/src/service"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// The password of `user create` and `user reset-password`, read from stdin if it's empty.
+	userPassword string
+
+	// The roles assigned by `user create`, e.g. --role admin for the first administrator.
+	userRoles []string
+
+	// The options of `user list`.
+	listUsername          string
+	listOffset, listLimit int
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage the users in the database",
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create <username>",
+	Short: "Create a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+
+		return withUserService(func(srv *service.UserService) error {
+			if srv.GetUserByName(args[0]) != nil {
+				return fmt.Errorf("user %s already exists", args[0])
+			}
+			for _, name := range userRoles {
+				if service.Role.GetRoleByName(name) == nil {
+					return fmt.Errorf("role %s not found", name)
+				}
+			}
+
+			u := model.UserModel{
+				Username: args[0],
+				Password: password,
+			}
+			if err := srv.Validate(&u); err != nil {
+				return err
+			}
+			if err := srv.Encrypt(&u); err != nil {
+				return err
+			}
+			if err := srv.CreateUser(&u); err != nil {
+				return err
+			}
+
+			fmt.Printf("Created user %s (id %d)\n", u.Username, u.ID)
+
+			for _, name := range userRoles {
+				if err := service.Role.AssignRole(u.ID, name); err != nil {
+					return err
+				}
+				fmt.Printf("Assigned role %s\n", name)
+			}
+			return nil
+		})
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the users",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withUserService(func(srv *service.UserService) error {
+			users, count, err := srv.GetUserList(listUsername, listOffset, listLimit)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tUSERNAME\tCREATED AT")
+			for _, u := range users {
+				fmt.Fprintf(w, "%d\t%s\t%s\n", u.ID, u.Username, u.CreatedAt.Format("2006-01-02 15:04:05"))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			fmt.Printf("Total %d users.\n", count)
+			return nil
+		})
+	},
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete <username>",
+	Short: "Delete a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withUserService(func(srv *service.UserService) error {
+			u := srv.GetUserByName(args[0])
+			if u == nil {
+				return fmt.Errorf("user %s not found", args[0])
+			}
+			if err := srv.DeleteUser(u.ID); err != nil {
+				return err
+			}
+
+			fmt.Printf("Deleted user %s\n", u.Username)
+			return nil
+		})
+	},
+}
+
+var userResetPasswordCmd = &cobra.Command{
+	Use:   "reset-password <username>",
+	Short: "Reset the password of a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+
+		return withUserService(func(srv *service.UserService) error {
+			u := srv.GetUserByName(args[0])
+			if u == nil {
+				return fmt.Errorf("user %s not found", args[0])
+			}
+
+			u.Password = password
+			if err := srv.Validate(u); err != nil {
+				return err
+			}
+			if err := srv.Encrypt(u); err != nil {
+				return err
+			}
+			if err := srv.UpdateUser(u); err != nil {
+				return err
+			}
+
+			fmt.Printf("Reset the password of user %s\n", u.Username)
+			return nil
+		})
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{userCreateCmd, userResetPasswordCmd} {
+		cmd.Flags().StringVarP(&userPassword, "password", "P", "", "The password, read from stdin if it's empty")
+	}
+	userCreateCmd.Flags().StringSliceVar(&userRoles, "role", nil, "Assign the role to the user, e.g. admin, can be repeated")
+	userListCmd.Flags().StringVar(&listUsername, "username", "", "Filter by the username")
+	userListCmd.Flags().IntVar(&listOffset, "offset", 0, "The offset of the first user")
+	userListCmd.Flags().IntVar(&listLimit, "limit", 20, "The max number of users, up to 100")
+
+	userCmd.AddCommand(userCreateCmd, userListCmd, userDeleteCmd, userResetPasswordCmd)
+}
+
+// withUserService runs fn with the user service of the configured database.
+func withUserService(fn func(srv *service.UserService) error) error {
+	if err := initConfig(); err != nil {
+		return err
+	}
+
+	if err := service.DB.Init(); err != nil {
+		return err
+	}
+	defer service.DB.Close()
+
+	return fn(service.User)
+}
+
+// readPassword returns the --password option, or reads a line from stdin.
+func readPassword() (string, error) {
+	if userPassword != "" {
+		return userPassword, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read the password from stdin: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v "github.com/moocss/apiserver/src/pkg/version"
+	"github.com/spf13/cobra"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version information",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info := v.Get()
+		marshalled, err := json.MarshalIndent(&info, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(marshalled))
+		return nil
+	},
+}